@@ -0,0 +1,2 @@
+ALTER TABLE refract.urls
+DROP COLUMN status;
@@ -0,0 +1,2 @@
+ALTER TABLE refract.urls
+ADD COLUMN status LowCardinality(String) DEFAULT 'active';
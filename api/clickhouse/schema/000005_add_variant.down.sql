@@ -0,0 +1,2 @@
+ALTER TABLE refract.clicks
+DROP COLUMN variant;
@@ -0,0 +1,3 @@
+-- A/B variant the visitor was sent to. Empty for links without variants.
+ALTER TABLE refract.clicks
+ADD COLUMN variant LowCardinality(String) DEFAULT '';
@@ -0,0 +1,7 @@
+ALTER TABLE refract.clicks
+DROP COLUMN is_bot,
+DROP COLUMN os_version,
+DROP COLUMN os,
+DROP COLUMN browser_version,
+DROP COLUMN browser,
+DROP COLUMN device_type;
@@ -0,0 +1,8 @@
+-- Derived from user_agent by the clicks worker.
+ALTER TABLE refract.clicks
+ADD COLUMN device_type LowCardinality(String) DEFAULT '',
+ADD COLUMN browser LowCardinality(String) DEFAULT '',
+ADD COLUMN browser_version String DEFAULT '',
+ADD COLUMN os LowCardinality(String) DEFAULT '',
+ADD COLUMN os_version String DEFAULT '',
+ADD COLUMN is_bot Bool DEFAULT false;
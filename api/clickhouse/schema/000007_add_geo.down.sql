@@ -0,0 +1,20 @@
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code;
+
+-- ClickHouse cannot drop a sorting key column, so url_daily_stats keeps
+-- country. It stays '' for new rows.
+
+ALTER TABLE refract.clicks
+DROP COLUMN as_org,
+DROP COLUMN asn,
+DROP COLUMN city,
+DROP COLUMN region,
+DROP COLUMN country;
@@ -0,0 +1,28 @@
+-- Resolved from ip_address by the clicks worker against the local GeoIP
+-- databases.
+ALTER TABLE refract.clicks
+ADD COLUMN country LowCardinality(String) DEFAULT '',
+ADD COLUMN region String DEFAULT '',
+ADD COLUMN city String DEFAULT '',
+ADD COLUMN asn UInt32 DEFAULT 0,
+ADD COLUMN as_org String DEFAULT '';
+
+-- Roll clicks up per country as well. Queries that ignore country keep
+-- working because the aggregate states merge across it.
+ALTER TABLE refract.url_daily_stats
+ADD COLUMN country LowCardinality(String) DEFAULT '',
+MODIFY ORDER BY (short_code, date, country);
+
+-- Swapping the query in place keeps the view attached, so no click
+-- inserted meanwhile misses the rollup.
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code, country;
@@ -0,0 +1,18 @@
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code, country;
+
+ALTER TABLE refract.url_daily_stats
+DROP COLUMN human_unique_ips,
+DROP COLUMN human_clicks;
+
+ALTER TABLE refract.clicks
+DROP COLUMN bot_reason;
@@ -0,0 +1,50 @@
+-- Why the worker classified a click as a bot: user_agent, ip_range or burst.
+ALTER TABLE refract.clicks
+ADD COLUMN bot_reason LowCardinality(String) DEFAULT '';
+
+-- Human clicks are rolled up next to all clicks so dashboards can leave
+-- bots out without reading raw clicks.
+ALTER TABLE refract.url_daily_stats
+ADD COLUMN human_clicks AggregateFunction(sum, UInt64),
+ADD COLUMN human_unique_ips AggregateFunction(uniq, IPv6);
+
+-- Swapping the query in place keeps the view attached, so no click
+-- inserted meanwhile misses the rollup.
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  sumState(toUInt64(NOT is_bot)) as human_clicks,
+  uniqStateIf(ip_address, NOT is_bot) as human_unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code, country;
+
+-- Backfill human counts from the raw clicks still retained. Clicks ingested
+-- after the view changed above are already counted by it.
+INSERT INTO refract.url_daily_stats (date, short_code, country, human_clicks, human_unique_ips)
+SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  sumState(toUInt64(1)) as human_clicks,
+  uniqState(ip_address) as human_unique_ips
+FROM refract.clicks
+WHERE NOT is_bot
+AND ingested_at < (
+  SELECT metadata_modification_time
+  FROM system.tables
+  WHERE database = 'refract' AND name = 'url_daily_stats_mv'
+)
+GROUP BY date, short_code, country;
+
+-- Days whose raw clicks are gone predate bot classification. Their clicks
+-- count as human so human-only dashboards do not drop to zero for them.
+INSERT INTO refract.url_daily_stats (date, short_code, country, human_clicks, human_unique_ips)
+SELECT date, short_code, country, clicks, unique_ips
+FROM refract.url_daily_stats
+WHERE date < (SELECT ifNull(minOrNull(toDate(clicked_at)), today()) FROM refract.clicks);
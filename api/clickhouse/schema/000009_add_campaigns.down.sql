@@ -0,0 +1,8 @@
+ALTER TABLE refract.clicks
+DROP COLUMN channel,
+DROP COLUMN referer_domain,
+DROP COLUMN utm_content,
+DROP COLUMN utm_term,
+DROP COLUMN utm_campaign,
+DROP COLUMN utm_medium,
+DROP COLUMN utm_source;
@@ -0,0 +1,11 @@
+-- UTM parameters of the visit, captured by the redirector.
+ALTER TABLE refract.clicks
+ADD COLUMN utm_source LowCardinality(String) DEFAULT '',
+ADD COLUMN utm_medium LowCardinality(String) DEFAULT '',
+ADD COLUMN utm_campaign String DEFAULT '',
+ADD COLUMN utm_term String DEFAULT '',
+ADD COLUMN utm_content String DEFAULT '',
+-- Normalized by the clicks worker from referer and utm_medium.
+-- channel is one of direct, search, social, email or referral.
+ADD COLUMN referer_domain LowCardinality(String) DEFAULT '',
+ADD COLUMN channel LowCardinality(String) DEFAULT '';
@@ -0,0 +1,19 @@
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  sumState(toUInt64(NOT is_bot)) as human_clicks,
+  uniqStateIf(ip_address, NOT is_bot) as human_unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code, country;
+
+-- ClickHouse cannot drop a sorting key column, so url_daily_stats keeps
+-- url_id. It stays 0 for new rows.
+
+ALTER TABLE refract.clicks
+DROP COLUMN url_id;
@@ -0,0 +1,25 @@
+-- The link a click was made on, set by the redirector. A short code can be
+-- reused by another link, so this is what ties clicks to an owner. Clicks
+-- recorded before it was added have 0 and are matched to links by when
+-- each link held the short code.
+ALTER TABLE refract.clicks
+ADD COLUMN url_id UInt64 DEFAULT 0;
+
+ALTER TABLE refract.url_daily_stats
+ADD COLUMN url_id UInt64 DEFAULT 0,
+MODIFY ORDER BY (short_code, date, country, url_id);
+
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  url_id,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  sumState(toUInt64(NOT is_bot)) as human_clicks,
+  uniqStateIf(ip_address, NOT is_bot) as human_unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code, country, url_id;
@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS refract.links;
+
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  url_id,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  sumState(toUInt64(NOT is_bot)) as human_clicks,
+  uniqStateIf(ip_address, NOT is_bot) as human_unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code, country, url_id;
+
+ALTER TABLE refract.url_daily_stats
+DROP COLUMN user_id;
+
+ALTER TABLE refract.clicks
+DROP INDEX idx_user_id,
+DROP COLUMN user_id;
@@ -0,0 +1,60 @@
+-- The owner of the link a click was made on, set by the redirector. Scoped
+-- analytics filter on it on the server, so their cost does not grow with
+-- the number of links an account has.
+ALTER TABLE refract.clicks
+ADD COLUMN user_id String DEFAULT '',
+ADD INDEX idx_user_id user_id TYPE bloom_filter GRANULARITY 4;
+
+ALTER TABLE refract.clicks
+MATERIALIZE INDEX idx_user_id;
+
+-- Not part of the sorting key, a url_id has one owner and rows without one
+-- are grouped by short code, which the backfill below maps to one owner.
+ALTER TABLE refract.url_daily_stats
+ADD COLUMN user_id String DEFAULT '';
+
+ALTER TABLE refract.url_daily_stats_mv
+MODIFY QUERY SELECT
+  toDate(clicked_at) as date,
+  short_code,
+  country,
+  url_id,
+  user_id,
+  sumState(toUInt64(1)) as clicks,
+  uniqState(ip_address) as unique_ips,
+  sumState(toUInt64(NOT is_bot)) as human_clicks,
+  uniqStateIf(ip_address, NOT is_bot) as human_unique_ips,
+  minState(clicked_at) as first_click_at,
+  maxState(clicked_at) as last_click_at
+FROM refract.clicks
+GROUP BY date, short_code, country, url_id, user_id;
+
+-- Rows recorded so far go to whoever holds the short code now, like the
+-- dashboards did before. Later rows come with their owner.
+ALTER TABLE refract.clicks
+UPDATE user_id = (
+  SELECT mapFromArrays(groupArray(short_code), groupArray(owner))
+  FROM (SELECT short_code, argMax(created_by, updated_at) AS owner FROM refract.urls GROUP BY short_code)
+)[short_code]
+WHERE user_id = '';
+
+ALTER TABLE refract.url_daily_stats
+UPDATE user_id = (
+  SELECT mapFromArrays(groupArray(short_code), groupArray(owner))
+  FROM (SELECT short_code, argMax(created_by, updated_at) AS owner FROM refract.urls GROUP BY short_code)
+)[short_code]
+WHERE user_id = '';
+
+-- Deleted links by ID. refract.urls keeps one row per short code, so a
+-- deleted link loses its tombstone there once another link takes its short
+-- code. Links are written here when they change, a link without a row has
+-- never been deleted.
+CREATE TABLE IF NOT EXISTS refract.links (
+  url_id UInt64,
+  created_by String,
+  short_code String,
+  is_deleted Bool DEFAULT false,
+  updated_at DateTime64(3) DEFAULT now64(3)
+)
+ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (created_by, url_id);
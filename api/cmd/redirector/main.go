@@ -14,6 +14,8 @@ import (
 	"github.com/SirNacou/refract/api/internal/config"
 	"github.com/SirNacou/refract/api/internal/features/urls/redirect"
 	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickhouse"
+	"github.com/SirNacou/refract/api/internal/infrastructure/geoip"
 	"github.com/SirNacou/refract/api/internal/infrastructure/persistence"
 	"github.com/SirNacou/refract/api/internal/infrastructure/publisher"
 	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
@@ -38,6 +40,13 @@ func main() {
 
 	repo := repository.NewPostgresURLRepository(db.Querier)
 
+	chClient, err := clickhouse.NewClient(&cfg.ClickHouse)
+	if err != nil {
+		fatal("ClickHouse", err)
+	}
+	defer chClient.Close()
+	chURLs := repository.NewClickHouseURLRepository(chClient)
+
 	valkey, err := cache.NewCache(ctx, &cfg.Valkey)
 	if err != nil {
 		fatal("Valkey", err)
@@ -46,6 +55,20 @@ func main() {
 
 	clicksPublisher := publisher.NewClicksPublisher(valkey.Client(), cfg.Valkey.ClicksStreamKey)
 
+	var geo *geoip.Reader
+	if cfg.GeoIPDatabasePath != "" {
+		geo, err = geoip.Open(cfg.GeoIPDatabasePath)
+		if err != nil {
+			fatal("GeoIP", err)
+		}
+		defer geo.Close()
+		if cfg.GeoIPReloadInterval > 0 {
+			go geo.Watch(ctx, cfg.GeoIPReloadInterval)
+		}
+	} else {
+		log.Println("GEOIP_DATABASE_PATH is not set, geo targets are disabled")
+	}
+
 	r := chi.NewRouter()
 
 	r.Use(middleware.RealIP)
@@ -53,8 +76,11 @@ func main() {
 	r.Use(middleware.Recoverer)
 
 	r.Get("/health", handleHealth)
-	redirectHandler := redirect.NewRedirectHandler(valkey, repo, clicksPublisher, cfg)
+	redirectHandler := redirect.NewRedirectHandler(valkey, repo, chURLs, clicksPublisher, geo, cfg)
 	r.Get("/{shortCode}", redirectHandler.Handle)
+	r.Get("/{shortCode}/*", redirectHandler.Handle)
+	r.Post("/{shortCode}", redirectHandler.HandleUnlock)
+	r.Post("/{shortCode}/*", redirectHandler.HandleUnlock)
 
 	srv := http.Server{
 		Addr:    fmt.Sprintf("0.0.0.0:%v", cfg.RedirectorPort),
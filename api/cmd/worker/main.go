@@ -14,8 +14,18 @@ import (
 
 	"github.com/SirNacou/refract/api/internal/config"
 	ingestclicks "github.com/SirNacou/refract/api/internal/features/clicks/ingest_clicks"
+	expireexports "github.com/SirNacou/refract/api/internal/features/exports/expire_exports"
+	runexports "github.com/SirNacou/refract/api/internal/features/exports/run_exports"
+	expireurls "github.com/SirNacou/refract/api/internal/features/urls/expire_urls"
+	purgedeletedurls "github.com/SirNacou/refract/api/internal/features/urls/purge_deleted_urls"
+	"github.com/SirNacou/refract/api/internal/infrastructure/botdetect"
 	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
 	"github.com/SirNacou/refract/api/internal/infrastructure/clickhouse"
+	"github.com/SirNacou/refract/api/internal/infrastructure/deadletter"
+	"github.com/SirNacou/refract/api/internal/infrastructure/filestore"
+	"github.com/SirNacou/refract/api/internal/infrastructure/geoip"
+	"github.com/SirNacou/refract/api/internal/infrastructure/persistence"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
 	"github.com/SirNacou/refract/api/internal/infrastructure/worker"
 )
 
@@ -40,12 +50,68 @@ func main() {
 	}
 	defer chClient.Close()
 
+	db, err := persistence.NewDB(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize DB: %v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresURLRepository(db.Querier)
+	chURLs := repository.NewClickHouseURLRepository(chClient)
+
 	handler := ingestclicks.NewCommandHandler(chClient)
 
-	clicksWorker, err := worker.NewClicksStreamWorker(ctx, valkey.Client(), handler, &cfg.Valkey)
+	geo := openGeoIP(ctx, cfg.GeoIPDatabasePath, cfg.GeoIPReloadInterval)
+	if geo != nil {
+		defer geo.Close()
+	} else {
+		log.Println("GEOIP_DATABASE_PATH is not set, clicks will have no location")
+	}
+	asn := openGeoIP(ctx, cfg.GeoIPASNDatabasePath, cfg.GeoIPReloadInterval)
+	if asn != nil {
+		defer asn.Close()
+	}
+
+	var botRanges botdetect.Ranges
+	if cfg.BotDetection.IPRangesPath != "" {
+		botRanges, err = botdetect.LoadRanges(cfg.BotDetection.IPRangesPath)
+		if err != nil {
+			log.Fatalf("Failed to load bot IP ranges: %v", err)
+		}
+	}
+	var bursts *botdetect.BurstDetector
+	if cfg.BotDetection.BurstLimit > 0 {
+		bursts = botdetect.NewBurstDetector(valkey.Client(), cfg.Valkey.ClickBurstKey, cfg.BotDetection.BurstLimit, cfg.BotDetection.BurstWindow)
+	}
+	enricher := worker.NewEnricher(geo, asn, botRanges, bursts)
+
+	deadLetters := deadletter.NewQueue(valkey.Client(), cfg.Valkey.DeadLetterStreamKey, cfg.Valkey.ClicksStreamKey)
+
+	clicksWorker, err := worker.NewClicksStreamWorker(ctx, valkey.Client(), handler, enricher, deadLetters, &cfg.Valkey)
 	if err != nil {
 		log.Fatalf("Failed to initialize Worker: %v", err)
 	}
+	log.Printf("Reading clicks as consumer %s", cfg.Valkey.Consumer)
+
+	purgeJob := worker.NewPeriodicJob("purge-deleted-urls", cfg.TrashPurgeInterval,
+		purgedeletedurls.NewCommandHandler(repo, cfg.TrashRetention).Handle).
+		WithLock(valkey.Client(), cfg.Valkey.JobLockKey, cfg.Valkey.Consumer)
+
+	expiryJob := worker.NewPeriodicJob("expire-urls", cfg.ExpirySweepInterval,
+		expireurls.NewCommandHandler(repo, valkey, chURLs, cfg.Valkey.RedirectKey).Handle).
+		WithLock(valkey.Client(), cfg.Valkey.JobLockKey, cfg.Valkey.Consumer)
+
+	exports := repository.NewPostgresExportRepository(db.Querier)
+	exportStore := filestore.New(cfg.Export.Dir)
+
+	// Not locked: every replica runs exports, Claim gives each to one of them.
+	exportJob := worker.NewPeriodicJob("run-exports", cfg.Export.PollInterval,
+		runexports.NewCommandHandler(exports, repo, exportStore, clickhouse.NewExporter(&cfg.ClickHouse), cfg.Export.TTL, cfg.Export.Timeout).Handle).
+		WithTimeout(cfg.Export.Timeout)
+
+	exportSweepJob := worker.NewPeriodicJob("expire-exports", cfg.Export.SweepInterval,
+		expireexports.NewCommandHandler(exports, exportStore).Handle).
+		WithLock(valkey.Client(), cfg.Valkey.JobLockKey, cfg.Valkey.Consumer)
 
 	// Start health server in a separate goroutine
 	healthServer := &http.Server{
@@ -75,6 +141,26 @@ func main() {
 		log.Fatalf("Failed to start Worker: %v", err)
 	}
 
+	err = purgeJob.Start(ctx)
+	if err != nil {
+		log.Fatalf("Failed to start purge job: %v", err)
+	}
+
+	err = expiryJob.Start(ctx)
+	if err != nil {
+		log.Fatalf("Failed to start expiry job: %v", err)
+	}
+
+	err = exportJob.Start(ctx)
+	if err != nil {
+		log.Fatalf("Failed to start export job: %v", err)
+	}
+
+	err = exportSweepJob.Start(ctx)
+	if err != nil {
+		log.Fatalf("Failed to start export sweep job: %v", err)
+	}
+
 	<-ctx.Done()
 
 	log.Println("Shutting down worker and health server...")
@@ -86,6 +172,22 @@ func main() {
 		log.Fatalf("Failed to stop Worker: %v", err)
 	}
 
+	if err := purgeJob.Stop(stopCtx); err != nil {
+		log.Printf("Failed to stop purge job: %v", err)
+	}
+
+	if err := expiryJob.Stop(stopCtx); err != nil {
+		log.Printf("Failed to stop expiry job: %v", err)
+	}
+
+	if err := exportJob.Stop(stopCtx); err != nil {
+		log.Printf("Failed to stop export job: %v", err)
+	}
+
+	if err := exportSweepJob.Stop(stopCtx); err != nil {
+		log.Printf("Failed to stop export sweep job: %v", err)
+	}
+
 	// Shutdown health server
 	healthShutdownCtx, healthCancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer healthCancel()
@@ -95,3 +197,20 @@ func main() {
 
 	wg.Wait()
 }
+
+// openGeoIP opens the database at path and watches it for changes. It
+// returns nil when path is empty.
+func openGeoIP(ctx context.Context, path string, reloadInterval time.Duration) *geoip.Reader {
+	if path == "" {
+		return nil
+	}
+
+	r, err := geoip.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open GeoIP database %s: %v", path, err)
+	}
+	if reloadInterval > 0 {
+		go r.Watch(ctx, reloadInterval)
+	}
+	return r
+}
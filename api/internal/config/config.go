@@ -1,6 +1,12 @@
 package config
 
-import "github.com/caarlos0/env/v11"
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+)
 
 type Config struct {
 	NodeID         int64  `env:"NODE_ID" envDefault:"0"`
@@ -10,19 +16,113 @@ type Config struct {
 	JwksURL        string `env:"JWKS_URL,required" envDefault:"http://frontend:3000/api/auth/jwks.json"`
 	DatabaseURL    string `env:"DATABASE_URL,required"`
 
+	// Users allowed to call the /admin endpoints.
+	AdminUserIDs []string `env:"ADMIN_USER_IDS" envSeparator:","`
+
+	// Used for links that do not set their own redirect status.
+	DefaultRedirectStatus int `env:"DEFAULT_REDIRECT_STATUS" envDefault:"307"`
+
+	// Deleted links stay restorable from the trash for TrashRetention before
+	// the worker purges them.
+	TrashRetention     time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+	TrashPurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL" envDefault:"1h"`
+
+	// How often the worker moves links past their expires_at to 'expired'.
+	ExpirySweepInterval time.Duration `env:"EXPIRY_SWEEP_INTERVAL" envDefault:"1m"`
+
+	// Local MaxMind-format country or city database used for geo-targeted
+	// redirects and click locations. Geo targets are ignored and clicks
+	// carry no location when unset.
+	GeoIPDatabasePath string `env:"GEOIP_DATABASE_PATH"`
+	// Optional MaxMind-format ASN database for the network of each click.
+	GeoIPASNDatabasePath string `env:"GEOIP_ASN_DATABASE_PATH"`
+	// How often the GeoIP files are checked for changes and reopened. 0
+	// disables reloading.
+	GeoIPReloadInterval time.Duration `env:"GEOIP_RELOAD_INTERVAL" envDefault:"5m"`
+
+	LinkPassword LinkPasswordConfig `envPrefix:"LINK_PASSWORD_"`
+
+	BotDetection BotDetectionConfig `envPrefix:"BOT_"`
+
+	Export ExportConfig `envPrefix:"EXPORT_"`
+
 	Valkey ValkeyConfig `envPrefix:"VALKEY_"`
 
 	ClickHouse ClickHouseConfig `envPrefix:"CLICKHOUSE_"`
 }
 
+type LinkPasswordConfig struct {
+	// CookieSecret signs the cookie that lets visitors through a protected
+	// link. When empty the redirector generates one per process, so cookies
+	// do not survive restarts or work across replicas.
+	CookieSecret string        `env:"COOKIE_SECRET"`
+	CookieTTL    time.Duration `env:"COOKIE_TTL" envDefault:"1h"`
+
+	// Failed attempts per link and client IP allowed within AttemptWindow.
+	MaxAttempts   int           `env:"MAX_ATTEMPTS" envDefault:"5"`
+	AttemptWindow time.Duration `env:"ATTEMPT_WINDOW" envDefault:"15m"`
+}
+
+type BotDetectionConfig struct {
+	// Optional file of known crawler CIDR ranges, one per line. Clicks from
+	// these networks count as bots.
+	IPRangesPath string `env:"IP_RANGES_PATH"`
+
+	// Clicks from one IP beyond BurstLimit within BurstWindow count as bots.
+	// 0 disables burst detection.
+	BurstLimit  int           `env:"BURST_LIMIT" envDefault:"30"`
+	BurstWindow time.Duration `env:"BURST_WINDOW" envDefault:"1m"`
+}
+
+type ExportConfig struct {
+	// Directory shared by the API and the worker where export files are
+	// written and downloaded from.
+	Dir string `env:"DIR" envDefault:"/var/lib/refract/exports"`
+
+	// Finished exports are deleted TTL after they complete.
+	TTL           time.Duration `env:"TTL" envDefault:"24h"`
+	SweepInterval time.Duration `env:"SWEEP_INTERVAL" envDefault:"1h"`
+
+	// How often the worker looks for queued exports, and how long one may
+	// run. Exports still running after Timeout are picked up again.
+	PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"5s"`
+	Timeout      time.Duration `env:"TIMEOUT" envDefault:"30m"`
+}
+
 type ValkeyConfig struct {
 	Host            string `env:"HOST,required"`
 	Port            int    `env:"PORT,required"`
 	RedirectKey     string `env:"REDIRECT_KEY,required"`
 	ClicksStreamKey string `env:"CLICKS_STREAM_KEY,required"`
 	ReadGroup       string `env:"READ_GROUP,required"`
-	Consumer        string `env:"CONSUMER,required"`
 	BatchSize       int    `env:"BATCH_SIZE" envDefault:"100"`
+
+	// Consumer names this worker in the read group and must be unique per
+	// replica. Defaults to the hostname, which is unique per pod or
+	// container.
+	Consumer string `env:"CONSUMER"`
+	// Pending clicks idle for ClaimMinIdle, usually left by a replica that
+	// died, are taken over by the other replicas.
+	ClaimMinIdle time.Duration `env:"CLAIM_MIN_IDLE" envDefault:"5m"`
+
+	// Counts failed password attempts per link and client IP.
+	PasswordAttemptsKey string `env:"PASSWORD_ATTEMPTS_KEY" envDefault:"password_attempts:{short_code}:{ip}"`
+
+	// Counts redirects of links with a click cap. Keyed by link ID, so a
+	// reused alias starts from zero.
+	ClickCountKey string `env:"CLICK_COUNT_KEY" envDefault:"click_count:{id}"`
+
+	// Counts clicks per IP for bot burst detection.
+	ClickBurstKey string `env:"CLICK_BURST_KEY" envDefault:"click_burst:{ip}"`
+
+	// Held by the replica running a periodic job, so that each run happens
+	// on one replica only.
+	JobLockKey string `env:"JOB_LOCK_KEY" envDefault:"job_lock:{job}"`
+
+	// Click entries delivered MaxDeliveries times without being ingested
+	// are moved to the dead-letter stream with the reason they failed.
+	DeadLetterStreamKey string `env:"DEAD_LETTER_STREAM_KEY" envDefault:"clicks_dead_letter"`
+	MaxDeliveries       int    `env:"MAX_DELIVERIES" envDefault:"10"`
 }
 
 type ClickHouseConfig struct {
@@ -31,6 +131,9 @@ type ClickHouseConfig struct {
 	User     string `env:"USER,required"`
 	Password string `env:"PASSWORD,required"`
 	Database string `env:"DATABASE_NAME,required"`
+
+	// HTTP interface port, used to stream exports as CSV and Parquet.
+	HTTPPort int `env:"HTTP_PORT" envDefault:"8123"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -39,5 +142,19 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	switch c.DefaultRedirectStatus {
+	case 301, 302, 307, 308:
+	default:
+		return nil, fmt.Errorf("DEFAULT_REDIRECT_STATUS must be 301, 302, 307 or 308, got %d", c.DefaultRedirectStatus)
+	}
+
+	if c.Valkey.Consumer == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("VALKEY_CONSUMER is not set and the hostname is unknown: %w", err)
+		}
+		c.Valkey.Consumer = host
+	}
+
 	return &c, nil
 }
@@ -8,14 +8,51 @@ import (
 	"time"
 )
 
-type Url struct {
+type Export struct {
 	ID          int64      `json:"id"`
-	ShortCode   string     `json:"short_code"`
-	OriginalUrl string     `json:"original_url"`
 	UserID      string     `json:"user_id"`
+	UrlID       *int64     `json:"url_id"`
+	ShortCode   *string    `json:"short_code"`
+	Format      string     `json:"format"`
+	RangeFrom   time.Time  `json:"range_from"`
+	RangeTo     time.Time  `json:"range_to"`
+	Status      string     `json:"status"`
+	FileName    *string    `json:"file_name"`
+	SizeBytes   *int64     `json:"size_bytes"`
+	Error       *string    `json:"error"`
 	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
 	ExpiresAt   *time.Time `json:"expires_at"`
-	Status      string     `json:"status"`
-	Title       string     `json:"title"`
+}
+
+type Url struct {
+	ID             int64      `json:"id"`
+	ShortCode      string     `json:"short_code"`
+	OriginalUrl    string     `json:"original_url"`
+	UserID         string     `json:"user_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Status         string     `json:"status"`
+	Title          string     `json:"title"`
+	Notes          string     `json:"notes"`
+	DeletedAt      *time.Time `json:"deleted_at"`
+	RedirectStatus *int16     `json:"redirect_status"`
+	ForwardQuery   bool       `json:"forward_query"`
+	ForwardPath    bool       `json:"forward_path"`
+	PasswordHash   *string    `json:"password_hash"`
+	MaxClicks      *int32     `json:"max_clicks"`
+	ActiveFrom     *time.Time `json:"active_from"`
+	FallbackUrl    *string    `json:"fallback_url"`
+	GeoTargets     []byte     `json:"geo_targets"`
+	DeviceTargets  []byte     `json:"device_targets"`
+	Variants       []byte     `json:"variants"`
+	StickyVariants bool       `json:"sticky_variants"`
+}
+
+type UserSetting struct {
+	UserID      string    `json:"user_id"`
+	FallbackUrl *string   `json:"fallback_url"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
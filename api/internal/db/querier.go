@@ -6,14 +6,60 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 type Querier interface {
+	// Takes the oldest pending export, or a running one started before
+	// stale_before whose worker likely died. SKIP LOCKED lets several workers
+	// claim without blocking each other.
+	ClaimExport(ctx context.Context, staleBefore *time.Time) (Export, error)
+	CompleteExport(ctx context.Context, arg CompleteExportParams) (Export, error)
 	CountActiveURLsByUser(ctx context.Context, userID string) (int64, error)
 	CountURLsByUser(ctx context.Context, userID string) (int64, error)
+	CreateExport(ctx context.Context, arg CreateExportParams) (Export, error)
 	CreateURL(ctx context.Context, arg CreateURLParams) (Url, error)
+	DeleteExpiredExports(ctx context.Context) ([]Export, error)
+	// Flips an active link that reached its click cap to 'expired'.
+	ExhaustURL(ctx context.Context, id int64) (Url, error)
+	// Flips a batch of active links past their expires_at to 'expired'.
+	// SKIP LOCKED lets several sweepers run without blocking each other.
+	ExpireDueURLs(ctx context.Context, limit int32) ([]Url, error)
+	FailExport(ctx context.Context, arg FailExportParams) (Export, error)
 	GetActiveURLByShortCode(ctx context.Context, shortCode string) (Url, error)
-	ListURLs(ctx context.Context, userID string) ([]Url, error)
+	GetExport(ctx context.Context, arg GetExportParams) (Export, error)
+	// Resolves a short code for the redirector whatever the link status, so
+	// inactive links can still send visitors to their fallback. The active row
+	// wins when an alias was reused.
+	GetRedirectURLByShortCode(ctx context.Context, shortCode string) (GetRedirectURLByShortCodeRow, error)
+	GetURLByID(ctx context.Context, arg GetURLByIDParams) (Url, error)
+	// Same as ListURLLifetimesByUser for one link.
+	GetURLLifetime(ctx context.Context, arg GetURLLifetimeParams) (GetURLLifetimeRow, error)
+	GetUserSettings(ctx context.Context, userID string) (UserSetting, error)
+	ListDeletedURLs(ctx context.Context, arg ListDeletedURLsParams) ([]Url, error)
+	ListExportsByUser(ctx context.Context, arg ListExportsByUserParams) ([]Export, error)
+	ListShortCodesByUser(ctx context.Context, userID string) ([]string, error)
+	// Lists the links of a user with the span each held its short code, for
+	// scoping click analytics. ended_at is when a newer link took the short
+	// code over and reused whether an older one held it before. Deleted links
+	// count as holders, their clicks were recorded while they held the code.
+	ListURLLifetimesByUser(ctx context.Context, userID string) ([]ListURLLifetimesByUserRow, error)
+	// Keyset pagination over idx_urls_user_id_created_at, newest first.
+	ListURLs(ctx context.Context, arg ListURLsParams) ([]Url, error)
+	// Same as ListURLs, oldest first.
+	ListURLsAsc(ctx context.Context, arg ListURLsAscParams) ([]Url, error)
+	ListURLsByIDs(ctx context.Context, arg ListURLsByIDsParams) ([]Url, error)
+	PurgeDeletedURLs(ctx context.Context, deletedAt *time.Time) (int64, error)
+	RestoreURL(ctx context.Context, arg RestoreURLParams) (Url, error)
+	// Matches substrings via ILIKE and typos via trigram word similarity, both
+	// served by idx_urls_search_trgm.
+	SearchURLs(ctx context.Context, arg SearchURLsParams) ([]SearchURLsRow, error)
+	SoftDeleteURL(ctx context.Context, arg SoftDeleteURLParams) (Url, error)
+	// Whether no newer link took the short code over. Unlike GetURLLifetime it
+	// also answers for deleted links.
+	URLHoldsShortCode(ctx context.Context, arg URLHoldsShortCodeParams) (bool, error)
+	UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error)
+	UpsertUserSettings(ctx context.Context, arg UpsertUserSettingsParams) (UserSetting, error)
 }
 
 var _ Querier = (*Queries)(nil)
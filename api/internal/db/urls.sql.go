@@ -15,6 +15,8 @@ SELECT COUNT(*)
 FROM urls
 WHERE user_id = $1
 AND status = 'active'
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW())
 `
 
 func (q *Queries) CountActiveURLsByUser(ctx context.Context, userID string) (int64, error) {
@@ -28,6 +30,7 @@ const countURLsByUser = `-- name: CountURLsByUser :one
 SELECT COUNT(*)
 FROM urls
 WHERE user_id = $1
+AND deleted_at IS NULL
 `
 
 func (q *Queries) CountURLsByUser(ctx context.Context, userID string) (int64, error) {
@@ -38,16 +41,28 @@ func (q *Queries) CountURLsByUser(ctx context.Context, userID string) (int64, er
 }
 
 const createURL = `-- name: CreateURL :one
-INSERT INTO urls (id, short_code, original_url, title, user_id, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title
+INSERT INTO urls (id, short_code, original_url, title, notes, user_id, expires_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
 `
 
 type CreateURLParams struct {
-	ID          int64      `json:"id"`
-	ShortCode   string     `json:"short_code"`
-	OriginalUrl string     `json:"original_url"`
-	Title       string     `json:"title"`
-	UserID      string     `json:"user_id"`
-	ExpiresAt   *time.Time `json:"expires_at"`
+	ID             int64      `json:"id"`
+	ShortCode      string     `json:"short_code"`
+	OriginalUrl    string     `json:"original_url"`
+	Title          string     `json:"title"`
+	Notes          string     `json:"notes"`
+	UserID         string     `json:"user_id"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	RedirectStatus *int16     `json:"redirect_status"`
+	ForwardQuery   bool       `json:"forward_query"`
+	ForwardPath    bool       `json:"forward_path"`
+	PasswordHash   *string    `json:"password_hash"`
+	MaxClicks      *int32     `json:"max_clicks"`
+	ActiveFrom     *time.Time `json:"active_from"`
+	FallbackUrl    *string    `json:"fallback_url"`
+	GeoTargets     []byte     `json:"geo_targets"`
+	DeviceTargets  []byte     `json:"device_targets"`
+	Variants       []byte     `json:"variants"`
+	StickyVariants bool       `json:"sticky_variants"`
 }
 
 func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, error) {
@@ -56,8 +71,20 @@ func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, erro
 		arg.ShortCode,
 		arg.OriginalUrl,
 		arg.Title,
+		arg.Notes,
 		arg.UserID,
 		arg.ExpiresAt,
+		arg.RedirectStatus,
+		arg.ForwardQuery,
+		arg.ForwardPath,
+		arg.PasswordHash,
+		arg.MaxClicks,
+		arg.ActiveFrom,
+		arg.FallbackUrl,
+		arg.GeoTargets,
+		arg.DeviceTargets,
+		arg.Variants,
+		arg.StickyVariants,
 	)
 	var i Url
 	err := row.Scan(
@@ -70,15 +97,132 @@ func (q *Queries) CreateURL(ctx context.Context, arg CreateURLParams) (Url, erro
 		&i.ExpiresAt,
 		&i.Status,
 		&i.Title,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.RedirectStatus,
+		&i.ForwardQuery,
+		&i.ForwardPath,
+		&i.PasswordHash,
+		&i.MaxClicks,
+		&i.ActiveFrom,
+		&i.FallbackUrl,
+		&i.GeoTargets,
+		&i.DeviceTargets,
+		&i.Variants,
+		&i.StickyVariants,
 	)
 	return i, err
 }
 
+const exhaustURL = `-- name: ExhaustURL :one
+UPDATE urls
+SET status = 'expired',
+    updated_at = NOW()
+WHERE id = $1
+AND status = 'active'
+AND deleted_at IS NULL
+RETURNING id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+`
+
+// Flips an active link that reached its click cap to 'expired'.
+func (q *Queries) ExhaustURL(ctx context.Context, id int64) (Url, error) {
+	row := q.db.QueryRow(ctx, exhaustURL, id)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.OriginalUrl,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Status,
+		&i.Title,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.RedirectStatus,
+		&i.ForwardQuery,
+		&i.ForwardPath,
+		&i.PasswordHash,
+		&i.MaxClicks,
+		&i.ActiveFrom,
+		&i.FallbackUrl,
+		&i.GeoTargets,
+		&i.DeviceTargets,
+		&i.Variants,
+		&i.StickyVariants,
+	)
+	return i, err
+}
+
+const expireDueURLs = `-- name: ExpireDueURLs :many
+UPDATE urls
+SET status = 'expired',
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id
+    FROM urls
+    WHERE status = 'active'
+    AND deleted_at IS NULL
+    AND expires_at <= NOW()
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+`
+
+// Flips a batch of active links past their expires_at to 'expired'.
+// SKIP LOCKED lets several sweepers run without blocking each other.
+func (q *Queries) ExpireDueURLs(ctx context.Context, limit int32) ([]Url, error) {
+	rows, err := q.db.Query(ctx, expireDueURLs, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Url{}
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.OriginalUrl,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.Status,
+			&i.Title,
+			&i.Notes,
+			&i.DeletedAt,
+			&i.RedirectStatus,
+			&i.ForwardQuery,
+			&i.ForwardPath,
+			&i.PasswordHash,
+			&i.MaxClicks,
+			&i.ActiveFrom,
+			&i.FallbackUrl,
+			&i.GeoTargets,
+			&i.DeviceTargets,
+			&i.Variants,
+			&i.StickyVariants,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getActiveURLByShortCode = `-- name: GetActiveURLByShortCode :one
-SELECT  id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title
+SELECT  id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
 FROM urls
 WHERE short_code = $1
 AND status = 'active'
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW())
 `
 
 func (q *Queries) GetActiveURLByShortCode(ctx context.Context, shortCode string) (Url, error) {
@@ -94,19 +238,383 @@ func (q *Queries) GetActiveURLByShortCode(ctx context.Context, shortCode string)
 		&i.ExpiresAt,
 		&i.Status,
 		&i.Title,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.RedirectStatus,
+		&i.ForwardQuery,
+		&i.ForwardPath,
+		&i.PasswordHash,
+		&i.MaxClicks,
+		&i.ActiveFrom,
+		&i.FallbackUrl,
+		&i.GeoTargets,
+		&i.DeviceTargets,
+		&i.Variants,
+		&i.StickyVariants,
+	)
+	return i, err
+}
+
+const getRedirectURLByShortCode = `-- name: GetRedirectURLByShortCode :one
+SELECT  urls.id, urls.short_code, urls.original_url, urls.user_id, urls.created_at, urls.updated_at, urls.expires_at, urls.status, urls.title, urls.notes, urls.deleted_at, urls.redirect_status, urls.forward_query, urls.forward_path, urls.password_hash, urls.max_clicks, urls.active_from, urls.fallback_url, urls.geo_targets, urls.device_targets, urls.variants, urls.sticky_variants,
+    user_settings.fallback_url AS user_fallback_url
+FROM urls
+LEFT JOIN user_settings ON user_settings.user_id = urls.user_id
+WHERE urls.short_code = $1
+AND urls.deleted_at IS NULL
+ORDER BY urls.status = 'active' DESC, urls.updated_at DESC
+LIMIT 1
+`
+
+type GetRedirectURLByShortCodeRow struct {
+	Url             Url     `json:"url"`
+	UserFallbackUrl *string `json:"user_fallback_url"`
+}
+
+// Resolves a short code for the redirector whatever the link status, so
+// inactive links can still send visitors to their fallback. The active row
+// wins when an alias was reused.
+func (q *Queries) GetRedirectURLByShortCode(ctx context.Context, shortCode string) (GetRedirectURLByShortCodeRow, error) {
+	row := q.db.QueryRow(ctx, getRedirectURLByShortCode, shortCode)
+	var i GetRedirectURLByShortCodeRow
+	err := row.Scan(
+		&i.Url.ID,
+		&i.Url.ShortCode,
+		&i.Url.OriginalUrl,
+		&i.Url.UserID,
+		&i.Url.CreatedAt,
+		&i.Url.UpdatedAt,
+		&i.Url.ExpiresAt,
+		&i.Url.Status,
+		&i.Url.Title,
+		&i.Url.Notes,
+		&i.Url.DeletedAt,
+		&i.Url.RedirectStatus,
+		&i.Url.ForwardQuery,
+		&i.Url.ForwardPath,
+		&i.Url.PasswordHash,
+		&i.Url.MaxClicks,
+		&i.Url.ActiveFrom,
+		&i.Url.FallbackUrl,
+		&i.Url.GeoTargets,
+		&i.Url.DeviceTargets,
+		&i.Url.Variants,
+		&i.Url.StickyVariants,
+		&i.UserFallbackUrl,
 	)
 	return i, err
 }
 
+const getURLByID = `-- name: GetURLByID :one
+SELECT  id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+FROM urls
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+`
+
+type GetURLByIDParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) GetURLByID(ctx context.Context, arg GetURLByIDParams) (Url, error) {
+	row := q.db.QueryRow(ctx, getURLByID, arg.ID, arg.UserID)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.OriginalUrl,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Status,
+		&i.Title,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.RedirectStatus,
+		&i.ForwardQuery,
+		&i.ForwardPath,
+		&i.PasswordHash,
+		&i.MaxClicks,
+		&i.ActiveFrom,
+		&i.FallbackUrl,
+		&i.GeoTargets,
+		&i.DeviceTargets,
+		&i.Variants,
+		&i.StickyVariants,
+	)
+	return i, err
+}
+
+const getURLLifetime = `-- name: GetURLLifetime :one
+SELECT  urls.id, urls.short_code, urls.original_url, urls.user_id, urls.created_at, urls.updated_at, urls.expires_at, urls.status, urls.title, urls.notes, urls.deleted_at, urls.redirect_status, urls.forward_query, urls.forward_path, urls.password_hash, urls.max_clicks, urls.active_from, urls.fallback_url, urls.geo_targets, urls.device_targets, urls.variants, urls.sticky_variants,
+    successor.created_at AS ended_at,
+    EXISTS (
+        SELECT 1
+        FROM urls earlier
+        WHERE earlier.short_code = urls.short_code
+        AND earlier.created_at < urls.created_at
+    ) AS reused
+FROM urls
+LEFT JOIN LATERAL (
+    SELECT created_at
+    FROM urls later
+    WHERE later.short_code = urls.short_code
+    AND later.created_at > urls.created_at
+    ORDER BY later.created_at
+    LIMIT 1
+) successor ON TRUE
+WHERE urls.id = $1
+AND urls.user_id = $2
+AND urls.deleted_at IS NULL
+`
+
+type GetURLLifetimeParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+type GetURLLifetimeRow struct {
+	Url     Url        `json:"url"`
+	EndedAt *time.Time `json:"ended_at"`
+	Reused  bool       `json:"reused"`
+}
+
+// Same as ListURLLifetimesByUser for one link.
+func (q *Queries) GetURLLifetime(ctx context.Context, arg GetURLLifetimeParams) (GetURLLifetimeRow, error) {
+	row := q.db.QueryRow(ctx, getURLLifetime, arg.ID, arg.UserID)
+	var i GetURLLifetimeRow
+	err := row.Scan(
+		&i.Url.ID,
+		&i.Url.ShortCode,
+		&i.Url.OriginalUrl,
+		&i.Url.UserID,
+		&i.Url.CreatedAt,
+		&i.Url.UpdatedAt,
+		&i.Url.ExpiresAt,
+		&i.Url.Status,
+		&i.Url.Title,
+		&i.Url.Notes,
+		&i.Url.DeletedAt,
+		&i.Url.RedirectStatus,
+		&i.Url.ForwardQuery,
+		&i.Url.ForwardPath,
+		&i.Url.PasswordHash,
+		&i.Url.MaxClicks,
+		&i.Url.ActiveFrom,
+		&i.Url.FallbackUrl,
+		&i.Url.GeoTargets,
+		&i.Url.DeviceTargets,
+		&i.Url.Variants,
+		&i.Url.StickyVariants,
+		&i.EndedAt,
+		&i.Reused,
+	)
+	return i, err
+}
+
+const listDeletedURLs = `-- name: ListDeletedURLs :many
+SELECT  id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+FROM urls
+WHERE user_id = $1
+AND deleted_at > $2
+ORDER BY deleted_at DESC
+`
+
+type ListDeletedURLsParams struct {
+	UserID    string     `json:"user_id"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+func (q *Queries) ListDeletedURLs(ctx context.Context, arg ListDeletedURLsParams) ([]Url, error) {
+	rows, err := q.db.Query(ctx, listDeletedURLs, arg.UserID, arg.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Url{}
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.OriginalUrl,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.Status,
+			&i.Title,
+			&i.Notes,
+			&i.DeletedAt,
+			&i.RedirectStatus,
+			&i.ForwardQuery,
+			&i.ForwardPath,
+			&i.PasswordHash,
+			&i.MaxClicks,
+			&i.ActiveFrom,
+			&i.FallbackUrl,
+			&i.GeoTargets,
+			&i.DeviceTargets,
+			&i.Variants,
+			&i.StickyVariants,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShortCodesByUser = `-- name: ListShortCodesByUser :many
+SELECT short_code
+FROM urls
+WHERE user_id = $1
+AND deleted_at IS NULL
+`
+
+func (q *Queries) ListShortCodesByUser(ctx context.Context, userID string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listShortCodesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var short_code string
+		if err := rows.Scan(&short_code); err != nil {
+			return nil, err
+		}
+		items = append(items, short_code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listURLLifetimesByUser = `-- name: ListURLLifetimesByUser :many
+SELECT  urls.id, urls.short_code, urls.original_url, urls.user_id, urls.created_at, urls.updated_at, urls.expires_at, urls.status, urls.title, urls.notes, urls.deleted_at, urls.redirect_status, urls.forward_query, urls.forward_path, urls.password_hash, urls.max_clicks, urls.active_from, urls.fallback_url, urls.geo_targets, urls.device_targets, urls.variants, urls.sticky_variants,
+    successor.created_at AS ended_at,
+    EXISTS (
+        SELECT 1
+        FROM urls earlier
+        WHERE earlier.short_code = urls.short_code
+        AND earlier.created_at < urls.created_at
+    ) AS reused
+FROM urls
+LEFT JOIN LATERAL (
+    SELECT created_at
+    FROM urls later
+    WHERE later.short_code = urls.short_code
+    AND later.created_at > urls.created_at
+    ORDER BY later.created_at
+    LIMIT 1
+) successor ON TRUE
+WHERE urls.user_id = $1
+AND urls.deleted_at IS NULL
+`
+
+type ListURLLifetimesByUserRow struct {
+	Url     Url        `json:"url"`
+	EndedAt *time.Time `json:"ended_at"`
+	Reused  bool       `json:"reused"`
+}
+
+// Lists the links of a user with the span each held its short code, for
+// scoping click analytics. ended_at is when a newer link took the short
+// code over and reused whether an older one held it before. Deleted links
+// count as holders, their clicks were recorded while they held the code.
+func (q *Queries) ListURLLifetimesByUser(ctx context.Context, userID string) ([]ListURLLifetimesByUserRow, error) {
+	rows, err := q.db.Query(ctx, listURLLifetimesByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListURLLifetimesByUserRow{}
+	for rows.Next() {
+		var i ListURLLifetimesByUserRow
+		if err := rows.Scan(
+			&i.Url.ID,
+			&i.Url.ShortCode,
+			&i.Url.OriginalUrl,
+			&i.Url.UserID,
+			&i.Url.CreatedAt,
+			&i.Url.UpdatedAt,
+			&i.Url.ExpiresAt,
+			&i.Url.Status,
+			&i.Url.Title,
+			&i.Url.Notes,
+			&i.Url.DeletedAt,
+			&i.Url.RedirectStatus,
+			&i.Url.ForwardQuery,
+			&i.Url.ForwardPath,
+			&i.Url.PasswordHash,
+			&i.Url.MaxClicks,
+			&i.Url.ActiveFrom,
+			&i.Url.FallbackUrl,
+			&i.Url.GeoTargets,
+			&i.Url.DeviceTargets,
+			&i.Url.Variants,
+			&i.Url.StickyVariants,
+			&i.EndedAt,
+			&i.Reused,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listURLs = `-- name: ListURLs :many
-SELECT  id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title
+SELECT  id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
 FROM urls
 WHERE user_id = $1
-ORDER BY created_at DESC
+AND deleted_at IS NULL
+AND ($2::text IS NULL OR status = $2)
+AND ($3::timestamptz IS NULL OR created_at >= $3)
+AND ($4::timestamptz IS NULL OR created_at < $4)
+AND ($5::timestamptz IS NULL OR expires_at >= $5)
+AND ($6::timestamptz IS NULL OR expires_at < $6)
+AND ($7::timestamptz IS NULL OR (created_at, id) < ($7, $8::bigint))
+ORDER BY created_at DESC, id DESC
+LIMIT $9
 `
 
-func (q *Queries) ListURLs(ctx context.Context, userID string) ([]Url, error) {
-	rows, err := q.db.Query(ctx, listURLs, userID)
+type ListURLsParams struct {
+	UserID          string     `json:"user_id"`
+	Status          *string    `json:"status"`
+	CreatedAfter    *time.Time `json:"created_after"`
+	CreatedBefore   *time.Time `json:"created_before"`
+	ExpiresAfter    *time.Time `json:"expires_after"`
+	ExpiresBefore   *time.Time `json:"expires_before"`
+	CursorCreatedAt *time.Time `json:"cursor_created_at"`
+	CursorID        *int64     `json:"cursor_id"`
+	PageSize        int32      `json:"page_size"`
+}
+
+// Keyset pagination over idx_urls_user_id_created_at, newest first.
+func (q *Queries) ListURLs(ctx context.Context, arg ListURLsParams) ([]Url, error) {
+	rows, err := q.db.Query(ctx, listURLs,
+		arg.UserID,
+		arg.Status,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.ExpiresAfter,
+		arg.ExpiresBefore,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +632,19 @@ func (q *Queries) ListURLs(ctx context.Context, userID string) ([]Url, error) {
 			&i.ExpiresAt,
 			&i.Status,
 			&i.Title,
+			&i.Notes,
+			&i.DeletedAt,
+			&i.RedirectStatus,
+			&i.ForwardQuery,
+			&i.ForwardPath,
+			&i.PasswordHash,
+			&i.MaxClicks,
+			&i.ActiveFrom,
+			&i.FallbackUrl,
+			&i.GeoTargets,
+			&i.DeviceTargets,
+			&i.Variants,
+			&i.StickyVariants,
 		); err != nil {
 			return nil, err
 		}
@@ -134,3 +655,442 @@ func (q *Queries) ListURLs(ctx context.Context, userID string) ([]Url, error) {
 	}
 	return items, nil
 }
+
+const listURLsAsc = `-- name: ListURLsAsc :many
+SELECT  id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+FROM urls
+WHERE user_id = $1
+AND deleted_at IS NULL
+AND ($2::text IS NULL OR status = $2)
+AND ($3::timestamptz IS NULL OR created_at >= $3)
+AND ($4::timestamptz IS NULL OR created_at < $4)
+AND ($5::timestamptz IS NULL OR expires_at >= $5)
+AND ($6::timestamptz IS NULL OR expires_at < $6)
+AND ($7::timestamptz IS NULL OR (created_at, id) > ($7, $8::bigint))
+ORDER BY created_at ASC, id ASC
+LIMIT $9
+`
+
+type ListURLsAscParams struct {
+	UserID          string     `json:"user_id"`
+	Status          *string    `json:"status"`
+	CreatedAfter    *time.Time `json:"created_after"`
+	CreatedBefore   *time.Time `json:"created_before"`
+	ExpiresAfter    *time.Time `json:"expires_after"`
+	ExpiresBefore   *time.Time `json:"expires_before"`
+	CursorCreatedAt *time.Time `json:"cursor_created_at"`
+	CursorID        *int64     `json:"cursor_id"`
+	PageSize        int32      `json:"page_size"`
+}
+
+// Same as ListURLs, oldest first.
+func (q *Queries) ListURLsAsc(ctx context.Context, arg ListURLsAscParams) ([]Url, error) {
+	rows, err := q.db.Query(ctx, listURLsAsc,
+		arg.UserID,
+		arg.Status,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.ExpiresAfter,
+		arg.ExpiresBefore,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Url{}
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.OriginalUrl,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.Status,
+			&i.Title,
+			&i.Notes,
+			&i.DeletedAt,
+			&i.RedirectStatus,
+			&i.ForwardQuery,
+			&i.ForwardPath,
+			&i.PasswordHash,
+			&i.MaxClicks,
+			&i.ActiveFrom,
+			&i.FallbackUrl,
+			&i.GeoTargets,
+			&i.DeviceTargets,
+			&i.Variants,
+			&i.StickyVariants,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listURLsByIDs = `-- name: ListURLsByIDs :many
+SELECT id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+FROM urls
+WHERE user_id = $1
+AND id = ANY($2::bigint[])
+AND deleted_at IS NULL
+`
+
+type ListURLsByIDsParams struct {
+	UserID string  `json:"user_id"`
+	Ids    []int64 `json:"ids"`
+}
+
+func (q *Queries) ListURLsByIDs(ctx context.Context, arg ListURLsByIDsParams) ([]Url, error) {
+	rows, err := q.db.Query(ctx, listURLsByIDs, arg.UserID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Url{}
+	for rows.Next() {
+		var i Url
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShortCode,
+			&i.OriginalUrl,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ExpiresAt,
+			&i.Status,
+			&i.Title,
+			&i.Notes,
+			&i.DeletedAt,
+			&i.RedirectStatus,
+			&i.ForwardQuery,
+			&i.ForwardPath,
+			&i.PasswordHash,
+			&i.MaxClicks,
+			&i.ActiveFrom,
+			&i.FallbackUrl,
+			&i.GeoTargets,
+			&i.DeviceTargets,
+			&i.Variants,
+			&i.StickyVariants,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedURLs = `-- name: PurgeDeletedURLs :execrows
+DELETE FROM urls
+WHERE deleted_at <= $1
+`
+
+func (q *Queries) PurgeDeletedURLs(ctx context.Context, deletedAt *time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeDeletedURLs, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreURL = `-- name: RestoreURL :one
+UPDATE urls
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at > $3
+RETURNING id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+`
+
+type RestoreURLParams struct {
+	ID        int64      `json:"id"`
+	UserID    string     `json:"user_id"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+func (q *Queries) RestoreURL(ctx context.Context, arg RestoreURLParams) (Url, error) {
+	row := q.db.QueryRow(ctx, restoreURL, arg.ID, arg.UserID, arg.DeletedAt)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.OriginalUrl,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Status,
+		&i.Title,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.RedirectStatus,
+		&i.ForwardQuery,
+		&i.ForwardPath,
+		&i.PasswordHash,
+		&i.MaxClicks,
+		&i.ActiveFrom,
+		&i.FallbackUrl,
+		&i.GeoTargets,
+		&i.DeviceTargets,
+		&i.Variants,
+		&i.StickyVariants,
+	)
+	return i, err
+}
+
+const searchURLs = `-- name: SearchURLs :many
+SELECT  urls.id, urls.short_code, urls.original_url, urls.user_id, urls.created_at, urls.updated_at, urls.expires_at, urls.status, urls.title, urls.notes, urls.deleted_at, urls.redirect_status, urls.forward_query, urls.forward_path, urls.password_hash, urls.max_clicks, urls.active_from, urls.fallback_url, urls.geo_targets, urls.device_targets, urls.variants, urls.sticky_variants,
+    word_similarity($1::text, title || ' ' || original_url || ' ' || (short_code COLLATE "default"))::real AS rank
+FROM urls
+WHERE user_id = $2
+AND deleted_at IS NULL
+AND (
+    (title || ' ' || original_url || ' ' || (short_code COLLATE "default")) ILIKE $3::text
+    OR $1::text <% (title || ' ' || original_url || ' ' || (short_code COLLATE "default"))
+)
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT $4
+OFFSET $5
+`
+
+type SearchURLsParams struct {
+	Query      string `json:"query"`
+	UserID     string `json:"user_id"`
+	Pattern    string `json:"pattern"`
+	PageSize   int32  `json:"page_size"`
+	PageOffset int32  `json:"page_offset"`
+}
+
+type SearchURLsRow struct {
+	Url  Url     `json:"url"`
+	Rank float32 `json:"rank"`
+}
+
+// Matches substrings via ILIKE and typos via trigram word similarity, both
+// served by idx_urls_search_trgm.
+func (q *Queries) SearchURLs(ctx context.Context, arg SearchURLsParams) ([]SearchURLsRow, error) {
+	rows, err := q.db.Query(ctx, searchURLs,
+		arg.Query,
+		arg.UserID,
+		arg.Pattern,
+		arg.PageSize,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchURLsRow{}
+	for rows.Next() {
+		var i SearchURLsRow
+		if err := rows.Scan(
+			&i.Url.ID,
+			&i.Url.ShortCode,
+			&i.Url.OriginalUrl,
+			&i.Url.UserID,
+			&i.Url.CreatedAt,
+			&i.Url.UpdatedAt,
+			&i.Url.ExpiresAt,
+			&i.Url.Status,
+			&i.Url.Title,
+			&i.Url.Notes,
+			&i.Url.DeletedAt,
+			&i.Url.RedirectStatus,
+			&i.Url.ForwardQuery,
+			&i.Url.ForwardPath,
+			&i.Url.PasswordHash,
+			&i.Url.MaxClicks,
+			&i.Url.ActiveFrom,
+			&i.Url.FallbackUrl,
+			&i.Url.GeoTargets,
+			&i.Url.DeviceTargets,
+			&i.Url.Variants,
+			&i.Url.StickyVariants,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteURL = `-- name: SoftDeleteURL :one
+UPDATE urls
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+RETURNING id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+`
+
+type SoftDeleteURLParams struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) SoftDeleteURL(ctx context.Context, arg SoftDeleteURLParams) (Url, error) {
+	row := q.db.QueryRow(ctx, softDeleteURL, arg.ID, arg.UserID)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.OriginalUrl,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Status,
+		&i.Title,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.RedirectStatus,
+		&i.ForwardQuery,
+		&i.ForwardPath,
+		&i.PasswordHash,
+		&i.MaxClicks,
+		&i.ActiveFrom,
+		&i.FallbackUrl,
+		&i.GeoTargets,
+		&i.DeviceTargets,
+		&i.Variants,
+		&i.StickyVariants,
+	)
+	return i, err
+}
+
+const urlHoldsShortCode = `-- name: URLHoldsShortCode :one
+SELECT NOT EXISTS (
+    SELECT 1
+    FROM urls later
+    WHERE later.short_code = $1
+    AND later.created_at > $2
+) AS holds
+`
+
+type URLHoldsShortCodeParams struct {
+	ShortCode string    `json:"short_code"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Whether no newer link took the short code over. Unlike GetURLLifetime it
+// also answers for deleted links.
+func (q *Queries) URLHoldsShortCode(ctx context.Context, arg URLHoldsShortCodeParams) (bool, error) {
+	row := q.db.QueryRow(ctx, urlHoldsShortCode, arg.ShortCode, arg.CreatedAt)
+	var holds bool
+	err := row.Scan(&holds)
+	return holds, err
+}
+
+const updateURL = `-- name: UpdateURL :one
+UPDATE urls
+SET original_url = $3,
+    title = $4,
+    notes = $5,
+    expires_at = $6,
+    status = $7,
+    redirect_status = $8,
+    forward_query = $9,
+    forward_path = $10,
+    password_hash = $11,
+    max_clicks = $12,
+    active_from = $13,
+    fallback_url = $14,
+    geo_targets = $15,
+    device_targets = $16,
+    variants = $17,
+    sticky_variants = $18,
+    updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+RETURNING id, short_code, original_url, user_id, created_at, updated_at, expires_at, status, title, notes, deleted_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants
+`
+
+type UpdateURLParams struct {
+	ID             int64      `json:"id"`
+	UserID         string     `json:"user_id"`
+	OriginalUrl    string     `json:"original_url"`
+	Title          string     `json:"title"`
+	Notes          string     `json:"notes"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Status         string     `json:"status"`
+	RedirectStatus *int16     `json:"redirect_status"`
+	ForwardQuery   bool       `json:"forward_query"`
+	ForwardPath    bool       `json:"forward_path"`
+	PasswordHash   *string    `json:"password_hash"`
+	MaxClicks      *int32     `json:"max_clicks"`
+	ActiveFrom     *time.Time `json:"active_from"`
+	FallbackUrl    *string    `json:"fallback_url"`
+	GeoTargets     []byte     `json:"geo_targets"`
+	DeviceTargets  []byte     `json:"device_targets"`
+	Variants       []byte     `json:"variants"`
+	StickyVariants bool       `json:"sticky_variants"`
+}
+
+func (q *Queries) UpdateURL(ctx context.Context, arg UpdateURLParams) (Url, error) {
+	row := q.db.QueryRow(ctx, updateURL,
+		arg.ID,
+		arg.UserID,
+		arg.OriginalUrl,
+		arg.Title,
+		arg.Notes,
+		arg.ExpiresAt,
+		arg.Status,
+		arg.RedirectStatus,
+		arg.ForwardQuery,
+		arg.ForwardPath,
+		arg.PasswordHash,
+		arg.MaxClicks,
+		arg.ActiveFrom,
+		arg.FallbackUrl,
+		arg.GeoTargets,
+		arg.DeviceTargets,
+		arg.Variants,
+		arg.StickyVariants,
+	)
+	var i Url
+	err := row.Scan(
+		&i.ID,
+		&i.ShortCode,
+		&i.OriginalUrl,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.Status,
+		&i.Title,
+		&i.Notes,
+		&i.DeletedAt,
+		&i.RedirectStatus,
+		&i.ForwardQuery,
+		&i.ForwardPath,
+		&i.PasswordHash,
+		&i.MaxClicks,
+		&i.ActiveFrom,
+		&i.FallbackUrl,
+		&i.GeoTargets,
+		&i.DeviceTargets,
+		&i.Variants,
+		&i.StickyVariants,
+	)
+	return i, err
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type ExportStatus = string
+
+const (
+	ExportPending   ExportStatus = "pending"
+	ExportRunning   ExportStatus = "running"
+	ExportCompleted ExportStatus = "completed"
+	ExportFailed    ExportStatus = "failed"
+)
+
+type ExportFormat = string
+
+const (
+	ExportCSV     ExportFormat = "csv"
+	ExportParquet ExportFormat = "parquet"
+)
+
+var ErrExportNotFound = errors.New("export not found")
+
+// Export is a raw click export of one link, or of every link of the user
+// when URLID is nil. The worker writes the file and it is kept until
+// ExpiresAt.
+type Export struct {
+	ID        SnowflakeID
+	UserID    string
+	URLID     *SnowflakeID
+	ShortCode string
+	Format    ExportFormat
+	From      time.Time
+	To        time.Time
+	Status    ExportStatus
+
+	// FileName is the name of the file in the export store once completed.
+	FileName  string
+	SizeBytes int64
+	Error     string
+
+	CreatedAt   time.Time
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time
+}
+
+func NewExport(userID string, url *URL, format ExportFormat, from, to time.Time) *Export {
+	e := &Export{
+		ID:     NewSnowflakeID(),
+		UserID: userID,
+		Format: format,
+		From:   from,
+		To:     to,
+		Status: ExportPending,
+	}
+	if url != nil {
+		e.URLID = &url.ID
+		e.ShortCode = url.ShortCode.String()
+	}
+	return e
+}
+
+// Extension is the file extension of the export format.
+func (e *Export) Extension() string {
+	if e.Format == ExportParquet {
+		return "parquet"
+	}
+	return "csv"
+}
+
+type ExportRepository interface {
+	Create(ctx context.Context, export *Export) error
+	Get(ctx context.Context, id SnowflakeID, userID string) (*Export, error)
+	ListByUser(ctx context.Context, userID string, limit int) ([]Export, error)
+	// Claim marks the oldest queued export as running. Running exports
+	// started before staleBefore are claimed again. Returns
+	// ErrExportNotFound when the queue is empty.
+	Claim(ctx context.Context, staleBefore time.Time) (*Export, error)
+	Complete(ctx context.Context, export *Export) error
+	Fail(ctx context.Context, export *Export) error
+	// DeleteExpired deletes and returns the exports past their ExpiresAt.
+	DeleteExpired(ctx context.Context) ([]Export, error)
+}
@@ -12,4 +12,4 @@ func NewSnowflakeID() SnowflakeID {
 
 func (id SnowflakeID) Int64() int64 {
 	return int64(id)
-}
\ No newline at end of file
+}
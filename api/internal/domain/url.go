@@ -2,6 +2,9 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -13,6 +16,13 @@ const (
 	Disabled Status = "disabled"
 )
 
+var (
+	ErrURLNotFound       = errors.New("url not found")
+	ErrShortCodeConflict = errors.New("short code is already in use")
+	ErrInvalidSchedule   = errors.New("expires_at must be after active_from")
+	ErrUnsafeDeepLink    = errors.New("deep link scheme is not allowed")
+)
+
 type URL struct {
 	ID          SnowflakeID
 	OriginalURL string
@@ -23,7 +33,93 @@ type URL struct {
 	ExpiresAt   *time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   *time.Time
 	Status      Status
+
+	// RedirectStatus is the HTTP status used when redirecting. Zero means
+	// the configured default.
+	RedirectStatus int
+
+	// ForwardQuery merges the visitor's query string into the destination.
+	ForwardQuery bool
+	// ForwardPath appends extra path segments after the short code to the
+	// destination path.
+	ForwardPath bool
+
+	// PasswordHash is the bcrypt hash of the link password. Empty means the
+	// link is public.
+	PasswordHash string
+
+	// MaxClicks is the number of redirects after which the link stops
+	// working. 0 means no cap.
+	MaxClicks int
+
+	// ActiveFrom is the launch time of a scheduled link. Nil means the link
+	// redirects as soon as it is created.
+	ActiveFrom *time.Time
+
+	// FallbackURL is where visitors go while the link is not redirecting.
+	// Empty means the owner's default from [UserSettings].
+	FallbackURL string
+
+	// GeoTargets maps upper-case ISO 3166-1 alpha-2 country codes to
+	// destinations that replace OriginalURL for visitors from that country.
+	GeoTargets map[string]string
+
+	// DeviceTargets maps a [Platform] to the destination for visitors on it.
+	// They take precedence over GeoTargets.
+	DeviceTargets map[Platform]DeviceTarget
+
+	// Variants split visitors that get the default destination between
+	// weighted URLs. Empty means everyone goes to OriginalURL.
+	Variants []Variant
+	// StickyVariants keeps a returning visitor on the variant they saw
+	// first.
+	StickyVariants bool
+}
+
+// Variant is one arm of an A/B split. Key identifies it in click analytics.
+type Variant struct {
+	Key    string `json:"key" validate:"required,max=32,printascii"`
+	URL    string `json:"url" validate:"required,url,max=2048"`
+	Weight int    `json:"weight" validate:"min=1,max=1000"`
+}
+
+// Platform is the device family a visitor is classified into from their
+// User-Agent.
+type Platform = string
+
+const (
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+	PlatformDesktop Platform = "desktop"
+)
+
+// DeviceTarget is the destination for one platform. DeepLink is an app URL
+// (e.g. myapp://product/42) tried first, with URL, usually the store page,
+// as the fallback when the app is not installed.
+type DeviceTarget struct {
+	URL      string `json:"url" validate:"required,url,max=2048"`
+	DeepLink string `json:"deep_link,omitempty" validate:"omitempty,uri,max=2048"`
+}
+
+// RedirectTarget is a link as the redirector sees it, whatever its status.
+type RedirectTarget struct {
+	URL
+	// UserFallbackURL is the owner's default fallback.
+	UserFallbackURL string
+}
+
+// ResolvedFallbackURL returns the link's own fallback or else the owner's default.
+func (t *RedirectTarget) ResolvedFallbackURL() string {
+	if t.URL.FallbackURL != "" {
+		return t.URL.FallbackURL
+	}
+	return t.UserFallbackURL
+}
+
+func (u *URL) PasswordProtected() bool {
+	return u.PasswordHash != ""
 }
 
 func NewURL(originalURL, title, notes, userID string, shortCode *ShortCode, expiresAt *time.Time) *URL {
@@ -44,10 +140,124 @@ func NewURL(originalURL, title, notes, userID string, shortCode *ShortCode, expi
 	}
 }
 
+// NormalizeGeoTargets upper-cases the country codes of targets so "de" and
+// "DE" address the same country.
+func NormalizeGeoTargets(targets map[string]string) map[string]string {
+	if targets == nil {
+		return nil
+	}
+	normalized := make(map[string]string, len(targets))
+	for country, dest := range targets {
+		normalized[strings.ToUpper(country)] = dest
+	}
+	return normalized
+}
+
+// unsafeDeepLinkSchemes run in the page that opens the deep link instead of
+// handing off to an app.
+var unsafeDeepLinkSchemes = map[string]bool{
+	"javascript": true,
+	"vbscript":   true,
+	"data":       true,
+	"file":       true,
+}
+
+// IsSafeDeepLink reports whether link can be opened from the redirect page.
+func IsSafeDeepLink(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && u.Scheme != "" && !unsafeDeepLinkSchemes[u.Scheme]
+}
+
+// ValidateDeviceTargets checks the deep links of targets. URL formats are
+// checked by the struct tags of [DeviceTarget].
+func ValidateDeviceTargets(targets map[Platform]DeviceTarget) error {
+	for _, t := range targets {
+		if t.DeepLink != "" && !IsSafeDeepLink(t.DeepLink) {
+			return ErrUnsafeDeepLink
+		}
+	}
+	return nil
+}
+
+// IsScheduled reports whether the URL has not launched yet at now.
+func (u *URL) IsScheduled(now time.Time) bool {
+	return u.ActiveFrom != nil && now.Before(*u.ActiveFrom)
+}
+
+// ValidateSchedule checks that the URL expires after it launches.
+func (u *URL) ValidateSchedule() error {
+	if u.ActiveFrom != nil && u.ExpiresAt != nil && !u.ExpiresAt.After(*u.ActiveFrom) {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+// IsExpired reports whether the URL's expiry date has passed at now.
+func (u *URL) IsExpired(now time.Time) bool {
+	return u.ExpiresAt != nil && !u.ExpiresAt.After(now)
+}
+
+// IsActive reports whether the URL redirects at now.
+func (u *URL) IsActive(now time.Time) bool {
+	return u.Status == Active && u.DeletedAt == nil && !u.IsExpired(now) && !u.IsScheduled(now)
+}
+
+// URLCursor marks the last row of a page in (created_at, id) order.
+type URLCursor struct {
+	CreatedAt time.Time
+	ID        SnowflakeID
+}
+
+// URLFilter narrows ListByUser. Nil fields are not applied.
+type URLFilter struct {
+	UserID        string
+	Status        *Status
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	ExpiresAfter  *time.Time
+	ExpiresBefore *time.Time
+	After         *URLCursor
+	Ascending     bool
+	Limit         int
+}
+
+// URLLifetime is a link with the span during which it held its short code.
+// A short code can be reused once its link is deleted or expired, so clicks
+// on it belong to whichever link held it at the time.
+type URLLifetime struct {
+	URL
+	// EndedAt is when a newer link took the short code over. Nil while the
+	// link still holds it.
+	EndedAt *time.Time
+	// Reused reports whether an older link held the short code before.
+	Reused bool
+}
+
+// URLSearchResult is a URL matched by a search, with its relevance in [0, 1].
+type URLSearchResult struct {
+	URL
+	Rank float32
+}
+
 type URLRepository interface {
-	ListByUser(ctx context.Context, userID string) ([]URL, error)
+	ListByUser(ctx context.Context, filter URLFilter) ([]URL, error)
+	Search(ctx context.Context, userID, query string, limit, offset int) ([]URLSearchResult, error)
 	GetActiveURLByShortCode(ctx context.Context, shortCode ShortCode) (*URL, error)
+	GetRedirectTarget(ctx context.Context, shortCode ShortCode) (*RedirectTarget, error)
+	ListShortCodesByUser(ctx context.Context, userID string) ([]ShortCode, error)
+	ListLifetimesByUser(ctx context.Context, userID string) ([]URLLifetime, error)
+	GetLifetime(ctx context.Context, id SnowflakeID, userID string) (*URLLifetime, error)
+	HoldsShortCode(ctx context.Context, url *URL) (bool, error)
+	GetByID(ctx context.Context, id SnowflakeID, userID string) (*URL, error)
+	ListByIDs(ctx context.Context, userID string, ids []SnowflakeID) ([]URL, error)
 	Create(ctx context.Context, url *URL) error
+	Update(ctx context.Context, url *URL) error
+	Delete(ctx context.Context, id SnowflakeID, userID string) (*URL, error)
+	Restore(ctx context.Context, id SnowflakeID, userID string, deletedAfter time.Time) (*URL, error)
+	ListDeletedByUser(ctx context.Context, userID string, deletedAfter time.Time) ([]URL, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+	ExpireDue(ctx context.Context, limit int) ([]URL, error)
+	Exhaust(ctx context.Context, id SnowflakeID) (*URL, error)
 	CountByUser(ctx context.Context, userID string) (int64, error)
 	CountActiveByUser(ctx context.Context, userID string) (int64, error)
 }
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// UserSettings holds per-user defaults. Users without a row get the zero
+// value.
+type UserSettings struct {
+	UserID      string
+	FallbackURL string
+	UpdatedAt   time.Time
+}
+
+type UserSettingsRepository interface {
+	Get(ctx context.Context, userID string) (*UserSettings, error)
+	Save(ctx context.Context, settings *UserSettings) error
+}
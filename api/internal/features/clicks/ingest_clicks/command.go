@@ -12,11 +12,45 @@ type Command struct {
 }
 
 type Click struct {
+	// URLID is the link the short code resolved to, 0 for clicks published
+	// before the redirector sent it.
+	URLID     int64
 	ShortCode string
 	ClickedAt time.Time // ← Parse from message
 	IPAddress string
 	UserAgent string
 	Referer   string
+	Variant   string
+
+	// UserID owns the link, empty for clicks published before the
+	// redirector sent it.
+	UserID string
+
+	UTMSource   string
+	UTMMedium   string
+	UTMCampaign string
+	UTMTerm     string
+	UTMContent  string
+
+	// Derived from UserAgent by the worker.
+	DeviceType     string
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	IsBot          bool
+	BotReason      string
+
+	// Derived from IPAddress by the worker.
+	Country string
+	Region  string
+	City    string
+	ASN     uint32
+	ASOrg   string
+
+	// Derived from Referer and UTMMedium by the worker.
+	RefererDomain string
+	Channel       string
 }
 
 type CommandHandler struct {
@@ -30,7 +64,7 @@ func NewCommandHandler(conn driver.Conn) *CommandHandler {
 }
 
 func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) error {
-	batch, err := h.chClient.PrepareBatch(ctx, "INSERT INTO clicks (short_code, clicked_at, ip_address, user_agent, referer)")
+	batch, err := h.chClient.PrepareBatch(ctx, "INSERT INTO clicks (url_id, user_id, short_code, clicked_at, ip_address, user_agent, referer, variant, device_type, browser, browser_version, os, os_version, is_bot, bot_reason, country, region, city, asn, as_org, utm_source, utm_medium, utm_campaign, utm_term, utm_content, referer_domain, channel)")
 	if err != nil {
 		return err
 	}
@@ -38,11 +72,33 @@ func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) error {
 
 	for _, click := range cmd.Clicks {
 		batch.Append(
+			uint64(click.URLID),
+			click.UserID,
 			click.ShortCode,
 			click.ClickedAt,
 			click.IPAddress,
 			click.UserAgent,
 			click.Referer,
+			click.Variant,
+			click.DeviceType,
+			click.Browser,
+			click.BrowserVersion,
+			click.OS,
+			click.OSVersion,
+			click.IsBot,
+			click.BotReason,
+			click.Country,
+			click.Region,
+			click.City,
+			click.ASN,
+			click.ASOrg,
+			click.UTMSource,
+			click.UTMMedium,
+			click.UTMCampaign,
+			click.UTMTerm,
+			click.UTMContent,
+			click.RefererDomain,
+			click.Channel,
 		)
 	}
 
@@ -0,0 +1,33 @@
+package listdeadletters
+
+import (
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Request struct {
+	Cursor string `query:"cursor" doc:"next_cursor of the previous page"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"500" default:"50"`
+}
+
+type Response struct {
+	Body *QueryResponse
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *Request) (*Response, error) {
+	res, err := h.query.Handle(ctx, &Query{Cursor: req.Cursor, Limit: req.Limit})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list dead letters", err)
+	}
+
+	return &Response{Body: res}, nil
+}
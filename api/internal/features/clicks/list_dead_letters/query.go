@@ -0,0 +1,68 @@
+package listdeadletters
+
+import (
+	"context"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/deadletter"
+)
+
+type Query struct {
+	Cursor string
+	Limit  int
+}
+
+type QueryResponse struct {
+	Total       int64        `json:"total"`
+	DeadLetters []DeadLetter `json:"dead_letters"`
+	NextCursor  string       `json:"next_cursor,omitempty" doc:"Pass as cursor to get the next page. Empty on the last page."`
+}
+
+type DeadLetter struct {
+	ID         string            `json:"id"`
+	OriginalID string            `json:"original_id" doc:"Entry ID in the clicks stream"`
+	Fields     map[string]string `json:"fields" doc:"Field values of the original entry"`
+	Reason     string            `json:"reason"`
+	Deliveries int64             `json:"deliveries"`
+	FailedAt   time.Time         `json:"failed_at"`
+}
+
+type QueryHandler struct {
+	queue *deadletter.Queue
+}
+
+func NewQueryHandler(queue *deadletter.Queue) *QueryHandler {
+	return &QueryHandler{queue: queue}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResponse, error) {
+	total, err := h.queue.Len(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	letters, err := h.queue.List(ctx, q.Cursor, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &QueryResponse{
+		Total:       total,
+		DeadLetters: make([]DeadLetter, 0, len(letters)),
+	}
+	for _, l := range letters {
+		res.DeadLetters = append(res.DeadLetters, DeadLetter{
+			ID:         l.ID,
+			OriginalID: l.OriginalID,
+			Fields:     l.Fields,
+			Reason:     l.Reason,
+			Deliveries: l.Deliveries,
+			FailedAt:   l.FailedAt,
+		})
+	}
+	if len(letters) == q.Limit {
+		res.NextCursor = letters[len(letters)-1].ID
+	}
+
+	return res, nil
+}
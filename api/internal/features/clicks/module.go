@@ -0,0 +1,49 @@
+package clicks
+
+import (
+	"net/http"
+
+	"github.com/SirNacou/refract/api/internal/config"
+	listdeadletters "github.com/SirNacou/refract/api/internal/features/clicks/list_dead_letters"
+	purgedeadletters "github.com/SirNacou/refract/api/internal/features/clicks/purge_dead_letters"
+	replaydeadletters "github.com/SirNacou/refract/api/internal/features/clicks/replay_dead_letters"
+	"github.com/SirNacou/refract/api/internal/infrastructure/deadletter"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+// Module holds the admin endpoints of click ingestion.
+type Module struct {
+	deadLetters *deadletter.Queue
+	cfg         *config.Config
+}
+
+func NewModule(valkey valkeyaside.CacheAsideClient, cfg *config.Config) *Module {
+	deadLetters := deadletter.NewQueue(valkey.Client(), cfg.Valkey.DeadLetterStreamKey, cfg.Valkey.ClicksStreamKey)
+
+	return &Module{deadLetters, cfg}
+}
+
+func (m *Module) RegisterRoutes(api huma.API) error {
+	grp := huma.NewGroup(api, "/clicks/dead-letters")
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "list-click-dead-letters",
+		Method:      http.MethodGet,
+		Path:        "/",
+	}, listdeadletters.NewHandler(listdeadletters.NewQueryHandler(m.deadLetters)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "replay-click-dead-letters",
+		Method:      http.MethodPost,
+		Path:        "/replay",
+	}, replaydeadletters.NewHandler(replaydeadletters.NewCommandHandler(m.deadLetters)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "purge-click-dead-letters",
+		Method:      http.MethodPost,
+		Path:        "/purge",
+	}, purgedeadletters.NewHandler(purgedeadletters.NewCommandHandler(m.deadLetters)).Handle)
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package purgedeadletters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/deadletter"
+)
+
+var errNoSelection = errors.New("either ids or all must be set")
+
+type Command struct {
+	IDs []string
+	All bool
+}
+
+type CommandResponse struct {
+	Purged int64 `json:"purged"`
+}
+
+type CommandHandler struct {
+	queue *deadletter.Queue
+}
+
+func NewCommandHandler(queue *deadletter.Queue) *CommandHandler {
+	return &CommandHandler{queue: queue}
+}
+
+// Handle drops the selected letters for good.
+func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*CommandResponse, error) {
+	if cmd.All == (len(cmd.IDs) > 0) {
+		return nil, errNoSelection
+	}
+
+	var (
+		n   int64
+		err error
+	)
+	if cmd.All {
+		n, err = h.queue.Purge(ctx)
+	} else {
+		n, err = h.queue.Delete(ctx, cmd.IDs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandResponse{Purged: n}, nil
+}
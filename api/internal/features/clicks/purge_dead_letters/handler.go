@@ -0,0 +1,39 @@
+package purgedeadletters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type PurgeRequest struct {
+	IDs []string `json:"ids,omitempty" maxItems:"1000" required:"false" doc:"Dead letters to delete"`
+	All bool     `json:"all,omitempty" required:"false" doc:"Delete every dead letter instead of ids"`
+}
+
+type Response struct {
+	Body *CommandResponse
+}
+
+type Handler struct {
+	cmd *CommandHandler
+}
+
+func NewHandler(cmd *CommandHandler) *Handler {
+	return &Handler{cmd: cmd}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *struct {
+	Body *PurgeRequest `json:"body" required:"true"`
+}) (*Response, error) {
+	res, err := h.cmd.Handle(ctx, &Command{IDs: req.Body.IDs, All: req.Body.All})
+	if errors.Is(err, errNoSelection) {
+		return nil, huma.Error400BadRequest("Set either ids or all", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to purge dead letters", err)
+	}
+
+	return &Response{Body: res}, nil
+}
@@ -0,0 +1,81 @@
+package replaydeadletters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/deadletter"
+)
+
+const pageSize = 500
+
+var errNoSelection = errors.New("either ids or all must be set")
+
+type Command struct {
+	IDs []string
+	All bool
+}
+
+type CommandResponse struct {
+	Replayed int `json:"replayed"`
+	Skipped  int `json:"skipped" doc:"Letters without fields to replay. They stay in the queue."`
+}
+
+type CommandHandler struct {
+	queue *deadletter.Queue
+}
+
+func NewCommandHandler(queue *deadletter.Queue) *CommandHandler {
+	return &CommandHandler{queue: queue}
+}
+
+// Handle puts the selected letters back on the clicks stream, where the
+// worker ingests them like new clicks.
+func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*CommandResponse, error) {
+	if cmd.All == (len(cmd.IDs) > 0) {
+		return nil, errNoSelection
+	}
+
+	res := &CommandResponse{}
+	if !cmd.All {
+		letters, err := h.queue.Get(ctx, cmd.IDs)
+		if err != nil {
+			return nil, err
+		}
+		return res, h.replay(ctx, letters, res)
+	}
+
+	// Letters replayed in this loop leave the queue, skipped ones stay, so
+	// the cursor moves past both. Entries that fail again while the loop
+	// runs are dead-lettered with newer IDs, so stopping at the newest
+	// letter seen up front replays each entry once.
+	last, err := h.queue.LastID(ctx)
+	if err != nil || last == "" {
+		return res, err
+	}
+
+	after := ""
+	for {
+		letters, err := h.queue.Range(ctx, after, last, pageSize)
+		if err != nil {
+			return res, err
+		}
+		if len(letters) == 0 {
+			return res, nil
+		}
+		if err := h.replay(ctx, letters, res); err != nil {
+			return res, err
+		}
+		after = letters[len(letters)-1].ID
+	}
+}
+
+func (h *CommandHandler) replay(ctx context.Context, letters []deadletter.Letter, res *CommandResponse) error {
+	n, err := h.queue.Replay(ctx, letters)
+	res.Replayed += n
+	if err != nil {
+		return err
+	}
+	res.Skipped += len(letters) - n
+	return nil
+}
@@ -0,0 +1,53 @@
+package createexport
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+var errInvalidRange = errors.New("from must be before to")
+
+type Command struct {
+	UserID string
+	// URLID limits the export to one link. Nil exports every link.
+	URLID  *domain.SnowflakeID
+	Format domain.ExportFormat
+	From   time.Time
+	To     time.Time
+}
+
+type CommandHandler struct {
+	repo domain.ExportRepository
+	urls domain.URLRepository
+}
+
+func NewCommandHandler(repo domain.ExportRepository, urls domain.URLRepository) *CommandHandler {
+	return &CommandHandler{repo: repo, urls: urls}
+}
+
+// Handle queues the export. The worker picks it up on its next poll.
+func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*domain.Export, error) {
+	if !cmd.From.Before(cmd.To) {
+		return nil, errInvalidRange
+	}
+
+	var u *domain.URL
+	if cmd.URLID != nil {
+		var err error
+		// GetByID only finds the caller's own links.
+		u, err = h.urls.GetByID(ctx, *cmd.URLID, cmd.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	export := domain.NewExport(cmd.UserID, u, cmd.Format, cmd.From, cmd.To)
+	if err := h.repo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
@@ -0,0 +1,103 @@
+package createexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type CreateRequest struct {
+	URLID  string    `json:"url_id,omitempty" required:"false" doc:"Export the clicks of this link. Omit to export every link."`
+	Format string    `json:"format" enum:"csv,parquet" default:"csv"`
+	From   time.Time `json:"from,omitempty" required:"false" doc:"Start of the range. Defaults to 30 days before to."`
+	To     time.Time `json:"to,omitempty" required:"false" doc:"End of the range. Defaults to now."`
+}
+
+type CreateResponse struct {
+	Body *CreateResponseBody
+}
+
+type CreateResponseBody struct {
+	ID        string    `json:"id"`
+	URLID     string    `json:"url_id,omitempty"`
+	ShortCode string    `json:"short_code,omitempty"`
+	Format    string    `json:"format"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Handler struct {
+	cmd *CommandHandler
+}
+
+func NewHandler(cmd *CommandHandler) *Handler {
+	return &Handler{cmd: cmd}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *struct {
+	Body *CreateRequest `json:"body" required:"true"`
+}) (*CreateResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	var urlID *domain.SnowflakeID
+	if req.Body.URLID != "" {
+		id, err := strconv.ParseInt(req.Body.URLID, 10, 64)
+		if err != nil {
+			return nil, huma.Error404NotFound("URL not found")
+		}
+		sid := domain.SnowflakeID(id)
+		urlID = &sid
+	}
+
+	to := req.Body.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := req.Body.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+
+	e, err := h.cmd.Handle(ctx, &Command{
+		UserID: userID,
+		URLID:  urlID,
+		Format: req.Body.Format,
+		From:   from,
+		To:     to,
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+	if errors.Is(err, errInvalidRange) {
+		return nil, huma.Error400BadRequest("Invalid time range", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to create export", err)
+	}
+
+	body := &CreateResponseBody{
+		ID:        fmt.Sprint(e.ID.Int64()),
+		ShortCode: e.ShortCode,
+		Format:    e.Format,
+		From:      e.From,
+		To:        e.To,
+		Status:    e.Status,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.URLID != nil {
+		body.URLID = fmt.Sprint(e.URLID.Int64())
+	}
+
+	return &CreateResponse{Body: body}, nil
+}
@@ -0,0 +1,107 @@
+package downloadexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	chunkSize    = 256 << 10
+	writeTimeout = 30 * time.Second
+)
+
+var contentTypes = map[domain.ExportFormat]string{
+	domain.ExportCSV:     "text/csv; charset=utf-8",
+	domain.ExportParquet: "application/vnd.apache.parquet",
+}
+
+type Request struct {
+	ID string `path:"id"`
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *Request) (*huma.StreamResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("Export not found")
+	}
+
+	res, err := h.query.Handle(ctx, &Query{ID: domain.SnowflakeID(id), UserID: userID})
+	if errors.Is(err, domain.ErrExportNotFound) {
+		return nil, huma.Error404NotFound("Export not found")
+	}
+	if errors.Is(err, errNotReady) {
+		return nil, huma.Error409Conflict("Export is not completed yet", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to open export", err)
+	}
+
+	e := res.Export
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			defer res.File.Close()
+			ctx := hctx.Context()
+
+			name := fmt.Sprintf("refract-clicks-%s.%s", req.ID, e.Extension())
+			if e.ShortCode != "" {
+				name = fmt.Sprintf("refract-clicks-%s-%s.%s", e.ShortCode, req.ID, e.Extension())
+			}
+			hctx.SetHeader("Content-Type", contentTypes[e.Format])
+			hctx.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+			if info, err := res.File.Stat(); err == nil {
+				hctx.SetHeader("Content-Length", strconv.FormatInt(info.Size(), 10))
+			}
+
+			w, ok := hctx.BodyWriter().(http.ResponseWriter)
+			if !ok {
+				slog.ErrorContext(ctx, "Export downloads need an http.ResponseWriter")
+				return
+			}
+			rc := http.NewResponseController(w)
+
+			buf := make([]byte, chunkSize)
+			for {
+				n, err := res.File.Read(buf)
+				if n > 0 {
+					// Large files take longer than the server WriteTimeout.
+					if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+						return
+					}
+					if _, err := w.Write(buf[:n]); err != nil {
+						return
+					}
+				}
+				if errors.Is(err, io.EOF) {
+					return
+				}
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to read export file", "id", req.ID, "error", err)
+					return
+				}
+			}
+		},
+	}, nil
+}
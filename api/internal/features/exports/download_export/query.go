@@ -0,0 +1,54 @@
+package downloadexport
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/filestore"
+)
+
+var errNotReady = errors.New("export is not completed")
+
+type Query struct {
+	ID     domain.SnowflakeID
+	UserID string
+}
+
+type QueryResult struct {
+	Export *domain.Export
+	// File is the open export file. The caller closes it.
+	File *os.File
+}
+
+type QueryHandler struct {
+	repo  domain.ExportRepository
+	store *filestore.Store
+}
+
+func NewQueryHandler(repo domain.ExportRepository, store *filestore.Store) *QueryHandler {
+	return &QueryHandler{repo: repo, store: store}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResult, error) {
+	e, err := h.repo.Get(ctx, q.ID, q.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if e.Status != domain.ExportCompleted {
+		return nil, errNotReady
+	}
+
+	f, err := h.store.Open(e.FileName)
+	// The file may be swept just before the row.
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryResult{Export: e, File: f}, nil
+}
@@ -0,0 +1,43 @@
+package expireexports
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/filestore"
+)
+
+// CommandHandler deletes exports past their expiry along with their files.
+type CommandHandler struct {
+	repo  domain.ExportRepository
+	store *filestore.Store
+}
+
+func NewCommandHandler(repo domain.ExportRepository, store *filestore.Store) *CommandHandler {
+	return &CommandHandler{repo: repo, store: store}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context) error {
+	exports, err := h.repo.DeleteExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(exports) == 0 {
+		return nil
+	}
+
+	for _, e := range exports {
+		if e.FileName == "" {
+			continue
+		}
+		// The row is gone, so a leftover file is only wasted disk space.
+		if err := h.store.Remove(e.FileName); err != nil {
+			slog.ErrorContext(ctx, "Failed to remove export file", "id", e.ID.Int64(), "file", e.FileName, "error", err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Expired exports", "count", len(exports))
+	return nil
+}
@@ -0,0 +1,87 @@
+package getexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Request struct {
+	ID string `path:"id"`
+}
+
+type Response struct {
+	Body *ResponseBody
+}
+
+type ResponseBody struct {
+	ID          string     `json:"id"`
+	URLID       string     `json:"url_id,omitempty"`
+	ShortCode   string     `json:"short_code,omitempty"`
+	Format      string     `json:"format"`
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	Status      string     `json:"status" enum:"pending,running,completed,failed"`
+	SizeBytes   int64      `json:"size_bytes,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty" doc:"Set once the export is completed"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" doc:"The file is deleted after this time"`
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *Request) (*Response, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("Export not found")
+	}
+
+	e, err := h.query.Handle(ctx, &Query{ID: domain.SnowflakeID(id), UserID: userID})
+	if errors.Is(err, domain.ErrExportNotFound) {
+		return nil, huma.Error404NotFound("Export not found")
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get export", err)
+	}
+
+	body := &ResponseBody{
+		ID:          req.ID,
+		ShortCode:   e.ShortCode,
+		Format:      e.Format,
+		From:        e.From,
+		To:          e.To,
+		Status:      e.Status,
+		SizeBytes:   e.SizeBytes,
+		Error:       e.Error,
+		CreatedAt:   e.CreatedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+	}
+	if e.URLID != nil {
+		body.URLID = fmt.Sprint(e.URLID.Int64())
+	}
+	if e.Status == domain.ExportCompleted {
+		body.DownloadURL = fmt.Sprintf("/api/exports/%s/download", req.ID)
+	}
+
+	return &Response{Body: body}, nil
+}
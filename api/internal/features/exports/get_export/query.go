@@ -0,0 +1,24 @@
+package getexport
+
+import (
+	"context"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+type Query struct {
+	ID     domain.SnowflakeID
+	UserID string
+}
+
+type QueryHandler struct {
+	repo domain.ExportRepository
+}
+
+func NewQueryHandler(repo domain.ExportRepository) *QueryHandler {
+	return &QueryHandler{repo: repo}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*domain.Export, error) {
+	return h.repo.Get(ctx, q.ID, q.UserID)
+}
@@ -0,0 +1,34 @@
+package listexports
+
+import (
+	"context"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Response struct {
+	Body *QueryResponse
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, _ *struct{}) (*Response, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	res, err := h.query.Handle(ctx, &Query{UserID: userID})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list exports", err)
+	}
+
+	return &Response{Body: res}, nil
+}
@@ -0,0 +1,78 @@
+package listexports
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+// limit is how many of the most recent exports are listed. Older ones
+// expire soon after anyway.
+const limit = 50
+
+type Query struct {
+	UserID string
+}
+
+type QueryResponse struct {
+	Exports []Export `json:"exports"`
+}
+
+type Export struct {
+	ID          string     `json:"id"`
+	URLID       string     `json:"url_id,omitempty"`
+	ShortCode   string     `json:"short_code,omitempty"`
+	Format      string     `json:"format"`
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	Status      string     `json:"status" enum:"pending,running,completed,failed"`
+	SizeBytes   int64      `json:"size_bytes,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty" doc:"Set once the export is completed"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" doc:"The file is deleted after this time"`
+}
+
+type QueryHandler struct {
+	repo domain.ExportRepository
+}
+
+func NewQueryHandler(repo domain.ExportRepository) *QueryHandler {
+	return &QueryHandler{repo: repo}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResponse, error) {
+	exports, err := h.repo.ListByUser(ctx, q.UserID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &QueryResponse{Exports: make([]Export, 0, len(exports))}
+	for _, e := range exports {
+		item := Export{
+			ID:          fmt.Sprint(e.ID.Int64()),
+			ShortCode:   e.ShortCode,
+			Format:      e.Format,
+			From:        e.From,
+			To:          e.To,
+			Status:      e.Status,
+			SizeBytes:   e.SizeBytes,
+			Error:       e.Error,
+			CreatedAt:   e.CreatedAt,
+			CompletedAt: e.CompletedAt,
+			ExpiresAt:   e.ExpiresAt,
+		}
+		if e.URLID != nil {
+			item.URLID = fmt.Sprint(e.URLID.Int64())
+		}
+		if e.Status == domain.ExportCompleted {
+			item.DownloadURL = fmt.Sprintf("/api/exports/%s/download", item.ID)
+		}
+		res.Exports = append(res.Exports, item)
+	}
+
+	return res, nil
+}
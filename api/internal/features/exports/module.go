@@ -0,0 +1,71 @@
+package exports
+
+import (
+	"net/http"
+
+	"github.com/SirNacou/refract/api/internal/config"
+	"github.com/SirNacou/refract/api/internal/domain"
+	createexport "github.com/SirNacou/refract/api/internal/features/exports/create_export"
+	downloadexport "github.com/SirNacou/refract/api/internal/features/exports/download_export"
+	getexport "github.com/SirNacou/refract/api/internal/features/exports/get_export"
+	listexports "github.com/SirNacou/refract/api/internal/features/exports/list_exports"
+	"github.com/SirNacou/refract/api/internal/infrastructure/filestore"
+	"github.com/SirNacou/refract/api/internal/infrastructure/persistence"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Module struct {
+	repo  domain.ExportRepository
+	urls  domain.URLRepository
+	store *filestore.Store
+	cfg   *config.Config
+}
+
+func NewModule(db *persistence.DB, cfg *config.Config) *Module {
+	repo := repository.NewPostgresExportRepository(db.Querier)
+	urls := repository.NewPostgresURLRepository(db.Querier)
+	store := filestore.New(cfg.Export.Dir)
+
+	return &Module{repo, urls, store, cfg}
+}
+
+func (m *Module) RegisterRoutes(api huma.API) error {
+	grp := huma.NewGroup(api, "/exports")
+
+	huma.Register(grp, huma.Operation{
+		OperationID:   "create-export",
+		Method:        http.MethodPost,
+		Path:          "/",
+		DefaultStatus: http.StatusAccepted,
+	}, createexport.NewHandler(createexport.NewCommandHandler(m.repo, m.urls)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "list-exports",
+		Method:      http.MethodGet,
+		Path:        "/",
+	}, listexports.NewHandler(listexports.NewQueryHandler(m.repo)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "get-export",
+		Method:      http.MethodGet,
+		Path:        "/{id}",
+	}, getexport.NewHandler(getexport.NewQueryHandler(m.repo)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "download-export",
+		Method:      http.MethodGet,
+		Path:        "/{id}/download",
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "The export file",
+				Content: map[string]*huma.MediaType{
+					"text/csv":                       {},
+					"application/vnd.apache.parquet": {},
+				},
+			},
+		},
+	}, downloadexport.NewHandler(downloadexport.NewQueryHandler(m.repo, m.store)).Handle)
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package runexports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickhouse"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickscope"
+	"github.com/SirNacou/refract/api/internal/infrastructure/filestore"
+)
+
+// exportQuery selects the raw clicks of the exported links in the range.
+// The {format} placeholder is replaced with a fixed string below, values
+// are bound by ClickHouse through query parameters.
+const exportQuery = `
+	SELECT
+		short_code, clicked_at, toString(ip_address) AS ip_address,
+		user_agent, device_type, browser, browser_version, os, os_version,
+		is_bot, bot_reason,
+		country, region, city, asn, as_org,
+		referer, referer_domain, channel,
+		utm_source, utm_medium, utm_campaign, utm_term, utm_content,
+		variant
+	FROM ` + clickscope.Clicks + `
+	WHERE clicked_at >= {from:DateTime64(3, 'UTC')} AND clicked_at < {to:DateTime64(3, 'UTC')}
+	ORDER BY clicked_at ASC
+	FORMAT {format}
+`
+
+var formats = map[domain.ExportFormat]string{
+	domain.ExportCSV:     "CSVWithNames",
+	domain.ExportParquet: "Parquet",
+}
+
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// CommandHandler runs the oldest queued export, writing the clicks streamed
+// from ClickHouse straight to the export store.
+type CommandHandler struct {
+	repo     domain.ExportRepository
+	urls     domain.URLRepository
+	store    *filestore.Store
+	exporter *clickhouse.Exporter
+	ttl      time.Duration
+	timeout  time.Duration
+}
+
+func NewCommandHandler(repo domain.ExportRepository, urls domain.URLRepository, store *filestore.Store, exporter *clickhouse.Exporter, ttl, timeout time.Duration) *CommandHandler {
+	return &CommandHandler{
+		repo:     repo,
+		urls:     urls,
+		store:    store,
+		exporter: exporter,
+		ttl:      ttl,
+		timeout:  timeout,
+	}
+}
+
+// Handle runs one export per call so each gets the whole run timeout.
+// Exports left running by a crashed worker are claimed again once they are
+// older than the timeout.
+func (h *CommandHandler) Handle(ctx context.Context) error {
+	e, err := h.repo.Claim(ctx, time.Now().Add(-h.timeout))
+	if errors.Is(err, domain.ErrExportNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Running export", "id", e.ID.Int64(), "format", e.Format, "short_code", e.ShortCode)
+
+	query := strings.ReplaceAll(exportQuery, "{format}", formats[e.Format])
+	name := fmt.Sprintf("%d.%s", e.ID.Int64(), e.Extension())
+
+	var size int64
+	scope, err := h.scope(ctx, e)
+	if err == nil {
+		params := scope.Params()
+		params["from"] = e.From.UTC().Format(timeLayout)
+		params["to"] = e.To.UTC().Format(timeLayout)
+
+		size, err = h.store.Write(name, func(w io.Writer) error {
+			_, err := h.exporter.Export(ctx, query, params, w)
+			return err
+		})
+	}
+
+	// Leave the export running on shutdown so another worker claims it.
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil
+	}
+
+	// The run context may be past its deadline.
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	expiresAt := time.Now().Add(h.ttl)
+	e.ExpiresAt = &expiresAt
+
+	if err != nil {
+		slog.ErrorContext(ctx, "Export failed", "id", e.ID.Int64(), "error", err)
+
+		e.Error = "export failed"
+		if errors.Is(err, context.DeadlineExceeded) {
+			e.Error = fmt.Sprintf("export took longer than %s, try a shorter range", h.timeout)
+		}
+		return h.repo.Fail(saveCtx, e)
+	}
+
+	e.FileName = name
+	e.SizeBytes = size
+	if err := h.repo.Complete(saveCtx, e); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Export completed", "id", e.ID.Int64(), "size_bytes", size)
+	return nil
+}
+
+// scope returns the clicks the export covers. Account exports are scoped
+// by owner on the server, so their parameters stay small however many links
+// the account has. Link exports leave out clicks made on a reused short
+// code before or after the user's link.
+func (h *CommandHandler) scope(ctx context.Context, e *domain.Export) (clickscope.Scope, error) {
+	if e.URLID == nil {
+		return clickscope.Owner(e.UserID), nil
+	}
+
+	link, err := h.urls.GetLifetime(ctx, *e.URLID, e.UserID)
+	if err != nil {
+		return clickscope.Scope{}, err
+	}
+	return clickscope.Link(link), nil
+}
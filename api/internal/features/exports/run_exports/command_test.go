@@ -0,0 +1,126 @@
+package runexports
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/config"
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickhouse"
+	"github.com/SirNacou/refract/api/internal/infrastructure/filestore"
+)
+
+type fakeExports struct {
+	domain.ExportRepository
+	queued    *domain.Export
+	completed *domain.Export
+}
+
+func (r *fakeExports) Claim(context.Context, time.Time) (*domain.Export, error) {
+	if r.queued == nil {
+		return nil, domain.ErrExportNotFound
+	}
+	e := r.queued
+	r.queued = nil
+	return e, nil
+}
+
+func (r *fakeExports) Complete(_ context.Context, e *domain.Export) error {
+	r.completed = e
+	return nil
+}
+
+// fakeURLs has no ListLifetimesByUser, an account export must not list the
+// links of the account.
+type fakeURLs struct {
+	domain.URLRepository
+	link *domain.URLLifetime
+}
+
+func (r *fakeURLs) GetLifetime(_ context.Context, id domain.SnowflakeID, userID string) (*domain.URLLifetime, error) {
+	if r.link == nil || r.link.ID != id || r.link.UserID != userID {
+		return nil, domain.ErrURLNotFound
+	}
+	return r.link, nil
+}
+
+// runExport runs e against a stand-in for the ClickHouse HTTP interface and
+// returns the query string of the request it got.
+func runExport(t *testing.T, e *domain.Export, urls domain.URLRepository) url.Values {
+	t.Helper()
+
+	var got url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		w.Write([]byte("short_code\n"))
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpPort, _ := strconv.Atoi(port)
+
+	exports := &fakeExports{queued: e}
+	exporter := clickhouse.NewExporter(&config.ClickHouseConfig{Host: host, HTTPPort: httpPort, Database: "refract"})
+	h := NewCommandHandler(exports, urls, filestore.New(t.TempDir()), exporter, time.Hour, time.Minute)
+
+	if err := h.Handle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if exports.completed == nil {
+		t.Fatalf("export did not complete: %s", e.Error)
+	}
+	return got
+}
+
+// The parameters of an account export do not depend on how many links the
+// account has, so large accounts stay within the URL size ClickHouse takes.
+func TestAccountExportScopesByOwner(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	e := &domain.Export{ID: 1, UserID: "user-a", Format: domain.ExportCSV, From: from, To: from.Add(24 * time.Hour)}
+
+	got := runExport(t, e, &fakeURLs{})
+
+	want := map[string]string{
+		"param_scope_user_id": "user-a",
+		"param_scope_url_id":  "0",
+		"param_from":          "2025-03-01 00:00:00.000",
+		"param_to":            "2025-03-02 00:00:00.000",
+	}
+	for name, w := range want {
+		if got.Get(name) != w {
+			t.Errorf("%s = %q, want %q", name, got.Get(name), w)
+		}
+	}
+	// database, the six scope parameters, from and to.
+	if len(got) != 9 {
+		t.Errorf("got %d query parameters, want 9: %v", len(got), got)
+	}
+}
+
+func TestLinkExportScopesByLink(t *testing.T) {
+	created := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	link := &domain.URLLifetime{URL: domain.URL{ID: 1001, ShortCode: "promo", UserID: "user-a", CreatedAt: created}}
+	e := &domain.Export{ID: 2, UserID: "user-a", URLID: &link.ID, Format: domain.ExportCSV, From: created, To: created.Add(24 * time.Hour)}
+
+	got := runExport(t, e, &fakeURLs{link: link})
+
+	want := map[string]string{
+		"param_scope_user_id":    "user-a",
+		"param_scope_url_id":     "1001",
+		"param_scope_short_code": "promo",
+	}
+	for name, w := range want {
+		if got.Get(name) != w {
+			t.Errorf("%s = %q, want %q", name, got.Get(name), w)
+		}
+	}
+}
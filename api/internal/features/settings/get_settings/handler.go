@@ -0,0 +1,44 @@
+package getsettings
+
+import (
+	"context"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type GetSettingsResponse struct {
+	Body *SettingsBody
+}
+
+type SettingsBody struct {
+	FallbackURL string `json:"fallback_url" doc:"Used by links without their own fallback while they do not redirect"`
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{
+		query: query,
+	}
+}
+
+func (h *Handler) Handle(ctx context.Context, _ *struct{}) (*GetSettingsResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	s, err := h.query.Handle(ctx, &Query{UserID: userID})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to load settings", err)
+	}
+
+	return &GetSettingsResponse{
+		Body: &SettingsBody{
+			FallbackURL: s.FallbackURL,
+		},
+	}, nil
+}
@@ -0,0 +1,25 @@
+package getsettings
+
+import (
+	"context"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+type Query struct {
+	UserID string
+}
+
+type QueryHandler struct {
+	repo domain.UserSettingsRepository
+}
+
+func NewQueryHandler(repo domain.UserSettingsRepository) *QueryHandler {
+	return &QueryHandler{
+		repo: repo,
+	}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*domain.UserSettings, error) {
+	return h.repo.Get(ctx, q.UserID)
+}
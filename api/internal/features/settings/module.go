@@ -0,0 +1,46 @@
+package settings
+
+import (
+	"net/http"
+
+	"github.com/SirNacou/refract/api/internal/config"
+	"github.com/SirNacou/refract/api/internal/domain"
+	getsettings "github.com/SirNacou/refract/api/internal/features/settings/get_settings"
+	updatesettings "github.com/SirNacou/refract/api/internal/features/settings/update_settings"
+	"github.com/SirNacou/refract/api/internal/infrastructure/persistence"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+type Module struct {
+	repo   domain.UserSettingsRepository
+	urls   domain.URLRepository
+	valkey valkeyaside.CacheAsideClient
+	cfg    *config.Config
+}
+
+func NewModule(db *persistence.DB, valkey valkeyaside.CacheAsideClient, cfg *config.Config) *Module {
+	repo := repository.NewPostgresUserSettingsRepository(db.Querier)
+	urls := repository.NewPostgresURLRepository(db.Querier)
+
+	return &Module{repo, urls, valkey, cfg}
+}
+
+func (m *Module) RegisterRoutes(api huma.API) error {
+	grp := huma.NewGroup(api, "/settings")
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "get-settings",
+		Method:      http.MethodGet,
+		Path:        "/",
+	}, getsettings.NewHandler(getsettings.NewQueryHandler(m.repo)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "update-settings",
+		Method:      http.MethodPut,
+		Path:        "/",
+	}, updatesettings.NewHandler(updatesettings.NewCommandHandler(m.repo, m.urls, m.valkey, m.cfg.Valkey.RedirectKey)).Handle)
+
+	return nil
+}
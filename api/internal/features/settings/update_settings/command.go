@@ -0,0 +1,75 @@
+package updatesettings
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/validator"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+// invalidateBatchSize bounds the number of keys per DEL.
+const invalidateBatchSize = 500
+
+type Command struct {
+	UserID      string `validate:"required"`
+	FallbackURL string `validate:"omitempty,url,max=2048"`
+}
+
+type CommandHandler struct {
+	repo        domain.UserSettingsRepository
+	urls        domain.URLRepository
+	valkey      valkeyaside.CacheAsideClient
+	redirectKey string
+}
+
+func NewCommandHandler(repo domain.UserSettingsRepository, urls domain.URLRepository, valkey valkeyaside.CacheAsideClient, redirectKey string) *CommandHandler {
+	return &CommandHandler{
+		repo:        repo,
+		urls:        urls,
+		valkey:      valkey,
+		redirectKey: redirectKey,
+	}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*domain.UserSettings, error) {
+	err := validator.GetValidator().StructCtx(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &domain.UserSettings{
+		UserID:      cmd.UserID,
+		FallbackURL: cmd.FallbackURL,
+	}
+	err = h.repo.Save(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cached redirects carry the resolved fallback, so every link of the
+	// user has to be reloaded.
+	codes, err := h.urls.ListShortCodesByUser(ctx, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(codes); start += invalidateBatchSize {
+		batch := codes[start:min(start+invalidateBatchSize, len(codes))]
+
+		keys := make([]string, len(batch))
+		for i, c := range batch {
+			keys[i] = cache.RedirectKey(h.redirectKey, c.String())
+		}
+
+		err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(keys...).Build()).Error()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to invalidate cached short codes", "user_id", cmd.UserID, "count", len(keys), "error", err)
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
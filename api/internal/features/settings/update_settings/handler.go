@@ -0,0 +1,57 @@
+package updatesettings
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-playground/validator/v10"
+)
+
+type UpdateSettingsRequest struct {
+	Body *UpdateSettingsBody `required:"true"`
+}
+
+type UpdateSettingsBody struct {
+	FallbackURL string `json:"fallback_url" maxLength:"2048" required:"false" doc:"Used by links without their own fallback while they do not redirect. Empty to disable."`
+}
+
+type UpdateSettingsResponse struct {
+	Body *UpdateSettingsBody
+}
+
+type Handler struct {
+	cmd *CommandHandler
+}
+
+func NewHandler(cmd *CommandHandler) *Handler {
+	return &Handler{
+		cmd: cmd,
+	}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *UpdateSettingsRequest) (*UpdateSettingsResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	s, err := h.cmd.Handle(ctx, &Command{
+		UserID:      userID,
+		FallbackURL: req.Body.FallbackURL,
+	})
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return nil, huma.Error400BadRequest("Invalid settings", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to update settings", err)
+	}
+
+	return &UpdateSettingsResponse{
+		Body: &UpdateSettingsBody{
+			FallbackURL: s.FallbackURL,
+		},
+	}, nil
+}
@@ -0,0 +1,53 @@
+package deleteurl
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+type Command struct {
+	ID     domain.SnowflakeID
+	UserID string
+}
+
+type CommandHandler struct {
+	repo        domain.URLRepository
+	valkey      valkeyaside.CacheAsideClient
+	chURLs      *repository.ClickHouseURLRepository
+	redirectKey string
+}
+
+func NewCommandHandler(repo domain.URLRepository, valkey valkeyaside.CacheAsideClient, chURLs *repository.ClickHouseURLRepository, redirectKey string) *CommandHandler {
+	return &CommandHandler{
+		repo:        repo,
+		valkey:      valkey,
+		chURLs:      chURLs,
+		redirectKey: redirectKey,
+	}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) error {
+	u, err := h.repo.Delete(ctx, cmd.ID, cmd.UserID)
+	if err != nil {
+		return err
+	}
+
+	key := cache.RedirectKey(h.redirectKey, u.ShortCode.String())
+	err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(key).Build()).Error()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate cached short code", "short_code", u.ShortCode, "error", err)
+		return err
+	}
+
+	err = h.chURLs.SaveCurrent(ctx, h.repo, u)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to mark URL as deleted in ClickHouse", "short_code", u.ShortCode, "error", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package deleteurl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type DeleteRequest struct {
+	ID string `path:"id"`
+}
+
+type Handler struct {
+	cmd *CommandHandler
+}
+
+func NewHandler(cmd *CommandHandler) *Handler {
+	return &Handler{
+		cmd: cmd,
+	}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *DeleteRequest) (*struct{}, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+
+	err = h.cmd.Handle(ctx, &Command{
+		ID:     domain.SnowflakeID(id),
+		UserID: userID,
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to delete URL", err)
+	}
+
+	return nil, nil
+}
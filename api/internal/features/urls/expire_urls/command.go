@@ -0,0 +1,68 @@
+package expireurls
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+const batchSize = 500
+
+// CommandHandler moves active links past their expiry date to 'expired',
+// drops their cached redirects and mirrors the new status to ClickHouse.
+type CommandHandler struct {
+	repo        domain.URLRepository
+	valkey      valkeyaside.CacheAsideClient
+	chURLs      *repository.ClickHouseURLRepository
+	redirectKey string
+}
+
+func NewCommandHandler(repo domain.URLRepository, valkey valkeyaside.CacheAsideClient, chURLs *repository.ClickHouseURLRepository, redirectKey string) *CommandHandler {
+	return &CommandHandler{
+		repo:        repo,
+		valkey:      valkey,
+		chURLs:      chURLs,
+		redirectKey: redirectKey,
+	}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context) error {
+	for {
+		urls, err := h.repo.ExpireDue(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(urls) == 0 {
+			return nil
+		}
+
+		slog.InfoContext(ctx, "Expired URLs", "count", len(urls))
+
+		keys := make([]string, 0, len(urls))
+		for _, u := range urls {
+			keys = append(keys, cache.RedirectKey(h.redirectKey, u.ShortCode.String()))
+		}
+
+		// The redirector already refuses expired links on a cache miss and
+		// caps the cache TTL at expires_at, so a failed purge only matters
+		// for entries cached before the expiry was changed.
+		err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(keys...).Build()).Error()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to purge cached short codes", "count", len(keys), "error", err)
+		}
+
+		err = h.chURLs.SaveAll(ctx, urls)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to update expired URLs in ClickHouse", "count", len(urls), "error", err)
+		}
+
+		if len(urls) < batchSize {
+			return nil
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 )
 
 type DashboardRequest struct {
+	IncludeBots bool `query:"include_bots" doc:"Count clicks from bots, crawlers and link previews"`
 }
 
 type DashboardResponse struct {
@@ -29,7 +30,8 @@ func (h *Handler) Handle(ctx context.Context, q *DashboardRequest) (*DashboardRe
 	}
 
 	res, err := h.query.Handle(ctx, &Query{
-		UserID: userID,
+		UserID:      userID,
+		IncludeBots: q.IncludeBots,
 	})
 	if err != nil {
 		return nil, err
@@ -3,15 +3,20 @@ package getdashboard
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickscope"
 )
 
 type Query struct {
 	UserID string
+	// IncludeBots counts clicks classified as bots. By default only human
+	// clicks are reported.
+	IncludeBots bool
 }
 type QueryResult struct {
 	TotalURLs      uint `json:"total_urls"`
@@ -67,19 +72,26 @@ func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResult, erro
 		return nil, err
 	}
 
+	chCtx := clickscope.Owner(q.UserID).Context(ctx)
+
+	clicksColumn := "human_clicks"
+	if q.IncludeBots {
+		clicksColumn = "clicks"
+	}
+
 	var totalClicks uint64
-	err = h.ch.QueryRow(ctx, `
-		SELECT sumMerge(clicks) as total_clicks
-		FROM refract.url_daily_stats
+	err = h.ch.QueryRow(chCtx, `
+		SELECT sumMerge(`+clicksColumn+`) as total_clicks
+		FROM `+clickscope.DailyStats+`
 	`).Scan(&totalClicks)
 	if err != nil {
 		return nil, err
 	}
 
 	var clicksThisWeek uint64
-	err = h.ch.QueryRow(ctx, `
-		SELECT sumMerge(clicks) as clicks_this_week
-		FROM refract.url_daily_stats
+	err = h.ch.QueryRow(chCtx, `
+		SELECT sumMerge(`+clicksColumn+`) as clicks_this_week
+		FROM `+clickscope.DailyStats+`
 		WHERE date >= today() - INTERVAL 7 DAY
 	`).Scan(&clicksThisWeek)
 	if err != nil {
@@ -87,9 +99,9 @@ func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResult, erro
 	}
 
 	clickTrends := make([]ClickTrend, 0)
-	rows, err := h.ch.Query(ctx, `
-		SELECT date, sumMerge(clicks) as clicks
-		FROM refract.url_daily_stats
+	rows, err := h.ch.Query(chCtx, `
+		SELECT date, sumMerge(`+clicksColumn+`) as clicks
+		FROM `+clickscope.DailyStats+`
 		WHERE date >= today() - INTERVAL 30 DAY
 		GROUP BY date
 		ORDER BY date ASC WITH FILL
@@ -109,74 +121,112 @@ func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResult, erro
 		clickTrends = append(clickTrends, ct)
 	}
 
-	rows, err = h.ch.Query(ctx, `
-	SELECT c.short_code, u.original_url, c.ip_address, c.clicked_at FROM refract.clicks c
-	LEFT JOIN refract.urls u ON c.short_code = u.short_code
-	ORDER BY c.clicked_at DESC
+	rows, err = h.ch.Query(chCtx, `
+	SELECT url_id, short_code, ip_address, clicked_at, device_type,
+		arrayStringConcat(arrayFilter(x -> x != '', [city, region, country]), ', ') AS location
+	FROM `+clickscope.Clicks+`
+	WHERE (? OR NOT is_bot)
+	ORDER BY clicked_at DESC
 	LIMIT 5
-	`)
+	`, q.IncludeBots)
 	if err != nil {
 		return nil, err
 	}
 
 	recentActivities := make([]RecentActivity, 0)
+	var recentIDs []domain.SnowflakeID
 	for rows.Next() {
 		var ra RecentActivity
-		if err := rows.Scan(&ra.ShortCode, &ra.OriginalURL, &ra.IPAddress, &ra.Timestamp); err != nil {
+		var urlID uint64
+		if err := rows.Scan(&urlID, &ra.ShortCode, &ra.IPAddress, &ra.Timestamp, &ra.Device, &ra.Location); err != nil {
 			return nil, err
 		}
-
 		recentActivities = append(recentActivities, ra)
+		recentIDs = append(recentIDs, domain.SnowflakeID(urlID))
 	}
 
-	rows, err = h.ch.Query(ctx, `
+	rows, err = h.ch.Query(chCtx, `
 		SELECT
-			u.original_url,
-			u.short_code,
-			sum(week.clicks) AS clicks,
-			groupArray(week.date) AS this_week_trends_dates,
-			groupArray(week.clicks) AS this_week_clicks
-		FROM refract.urls u
-		JOIN (
+			url_id,
+			short_code,
+			sum(day_clicks) AS clicks,
+			groupArray(date) AS this_week_trends_dates,
+			groupArray(day_clicks) AS this_week_clicks
+		FROM (
 			SELECT
+				url_id,
 				short_code,
 				date,
-				sumMerge(clicks) AS clicks
-			FROM refract.url_daily_stats
+				sumMerge(`+clicksColumn+`) AS day_clicks
+			FROM `+clickscope.DailyStats+`
 			WHERE date >= today() - INTERVAL 7 DAY
-			GROUP BY short_code, date
-		) week ON u.short_code = week.short_code
-		WHERE u.created_by = ?
-		GROUP BY u.short_code, u.original_url
+			GROUP BY url_id, short_code, date
+			ORDER BY date
+		)
+		GROUP BY url_id, short_code
 		ORDER BY clicks DESC
 		LIMIT 10
-	`, q.UserID)
+	`)
 	if err != nil {
 		return nil, err
 	}
 
-	topURLs := make([]TopURL, 0)
+	type topRow struct {
+		URLID          uint64      `ch:"url_id"`
+		ShortCode      string      `ch:"short_code"`
+		Clicks         uint64      `ch:"clicks"`
+		ThisWeekTrends []time.Time `ch:"this_week_trends_dates"`
+		ThisWeekClicks []uint64    `ch:"this_week_clicks"`
+	}
+	var top []topRow
 	for rows.Next() {
-		var tu struct {
-			OriginalURL    string      `ch:"original_url"`
-			ShortCode      string      `ch:"short_code"`
-			Clicks         uint64      `ch:"clicks"`
-			ThisWeekTrends []time.Time `ch:"this_week_trends_dates"`
-			ThisWeekClicks []uint64    `ch:"this_week_clicks"`
-		}
+		var tu topRow
 		if err := rows.ScanStruct(&tu); err != nil {
 			slog.ErrorContext(ctx, "Failed to scan top URL", "error", err)
 			return nil, err
 		}
+		top = append(top, tu)
+	}
+
+	// Destinations come from the caller's own links, never from whoever
+	// holds the short code now. Clicks recorded before they carried the link
+	// ID have no destination.
+	ids := slices.Clone(recentIDs)
+	for _, tu := range top {
+		ids = append(ids, domain.SnowflakeID(tu.URLID))
+	}
+	links, err := h.repo.ListByIDs(ctx, q.UserID, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[domain.SnowflakeID]*domain.URL, len(links))
+	for i := range links {
+		byID[links[i].ID] = &links[i]
+	}
+
+	for i, id := range recentIDs {
+		if l, ok := byID[id]; ok {
+			recentActivities[i].OriginalURL = l.OriginalURL
+		}
+	}
+
+	topURLs := make([]TopURL, 0)
+	for _, tu := range top {
+		var originalURL string
+		if tu.URLID != 0 {
+			l, ok := byID[domain.SnowflakeID(tu.URLID)]
+			if !ok {
+				continue
+			}
+			originalURL = l.OriginalURL
+		}
 		topURLs = append(topURLs, TopURL{
-			OriginalURL: tu.OriginalURL,
+			OriginalURL: originalURL,
 			ShortURL:    strings.Join([]string{h.defaultBaseURL, tu.ShortCode}, "/"),
 			Clicks:      tu.Clicks,
 			ThisWeekTrends: func() []ClickTrend {
 				trends := make([]ClickTrend, 0, len(tu.ThisWeekTrends))
 				for i, t := range tu.ThisWeekTrends {
-					slog.InfoContext(ctx, "ThisWeekTrends item", "key", t)
-
 					trends = append(trends, ClickTrend{
 						Date:   t,
 						Clicks: tu.ThisWeekClicks[i],
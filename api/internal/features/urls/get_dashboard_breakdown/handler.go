@@ -0,0 +1,63 @@
+package getdashboardbreakdown
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Request struct {
+	Dimension   string    `path:"dimension" enum:"sources,mediums,campaigns,referrers,channels"`
+	From        time.Time `query:"from" doc:"Start of the range. Defaults to 30 days before to."`
+	To          time.Time `query:"to" doc:"End of the range. Defaults to now."`
+	Limit       int       `query:"limit" minimum:"1" maximum:"100" default:"10"`
+	IncludeBots bool      `query:"include_bots" doc:"Count clicks from bots, crawlers and link previews"`
+}
+
+type Response struct {
+	Body *QueryResult
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *Request) (*Response, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+
+	res, err := h.query.Handle(ctx, &Query{
+		UserID:      userID,
+		Dimension:   Dimension(req.Dimension),
+		From:        from,
+		To:          to,
+		Limit:       req.Limit,
+		IncludeBots: req.IncludeBots,
+	})
+	if errors.Is(err, errInvalidRange) {
+		return nil, huma.Error400BadRequest("Invalid time range", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to query breakdown", err)
+	}
+
+	return &Response{Body: res}, nil
+}
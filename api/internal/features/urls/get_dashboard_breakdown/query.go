@@ -0,0 +1,70 @@
+package getdashboardbreakdown
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickscope"
+)
+
+var errInvalidRange = errors.New("from must be before to")
+
+type Dimension string
+
+// dimensions maps each breakdown to the refract.clicks column it groups by.
+// Only these columns are ever put into SQL.
+var dimensions = map[Dimension]string{
+	"sources":   "utm_source",
+	"mediums":   "utm_medium",
+	"campaigns": "utm_campaign",
+	"referrers": "referer_domain",
+	"channels":  "channel",
+}
+
+type Query struct {
+	UserID      string
+	Dimension   Dimension
+	From        time.Time
+	To          time.Time
+	Limit       int
+	IncludeBots bool
+}
+
+type QueryResult struct {
+	Dimension Dimension `json:"dimension"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Items     []Item    `json:"items"`
+}
+
+type Item = clickscope.Count
+
+type QueryHandler struct {
+	ch clickhouse.Conn
+}
+
+func NewQueryHandler(ch clickhouse.Conn) *QueryHandler {
+	return &QueryHandler{ch: ch}
+}
+
+// Handle groups the raw clicks of all the caller's links, which are kept for
+// 30 days, by the requested dimension.
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResult, error) {
+	if !q.From.Before(q.To) {
+		return nil, errInvalidRange
+	}
+
+	items, err := clickscope.Owner(q.UserID).Breakdown(ctx, h.ch, dimensions[q.Dimension], q.From, q.To, q.IncludeBots, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryResult{
+		Dimension: q.Dimension,
+		From:      q.From,
+		To:        q.To,
+		Items:     items,
+	}, nil
+}
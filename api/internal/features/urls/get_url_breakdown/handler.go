@@ -0,0 +1,76 @@
+package geturlbreakdown
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Request struct {
+	ID        string    `path:"id"`
+	Dimension string    `path:"dimension" enum:"devices,browsers,browser-versions,os,os-versions,countries,regions,cities,networks,sources,mediums,campaigns,referrers,channels"`
+	From      time.Time `query:"from" doc:"Start of the range. Defaults to 30 days before to."`
+	To        time.Time `query:"to" doc:"End of the range. Defaults to now."`
+	Limit     int       `query:"limit" minimum:"1" maximum:"250" default:"10"`
+
+	IncludeBots bool `query:"include_bots" doc:"Count clicks from bots, crawlers and link previews"`
+}
+
+type Response struct {
+	Body *QueryResult
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *Request) (*Response, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+
+	res, err := h.query.Handle(ctx, &Query{
+		ID:          domain.SnowflakeID(id),
+		UserID:      userID,
+		Dimension:   Dimension(req.Dimension),
+		From:        from,
+		To:          to,
+		Limit:       req.Limit,
+		IncludeBots: req.IncludeBots,
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+	if errors.Is(err, errInvalidRange) {
+		return nil, huma.Error400BadRequest("Invalid time range", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to query breakdown", err)
+	}
+
+	return &Response{Body: res}, nil
+}
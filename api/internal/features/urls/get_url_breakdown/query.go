@@ -0,0 +1,108 @@
+package geturlbreakdown
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickscope"
+)
+
+var errInvalidRange = errors.New("from must be before to")
+
+type Dimension string
+
+// dimensions maps each breakdown to the refract.clicks expression it groups
+// by. Only these expressions are ever put into SQL.
+var dimensions = map[Dimension]string{
+	"devices":          "device_type",
+	"browsers":         "browser",
+	"browser-versions": "trim(concat(browser, ' ', browser_version))",
+	"os":               "os",
+	"os-versions":      "trim(concat(os, ' ', os_version))",
+	"regions":          "if(region = '', '', concat(region, ', ', country))",
+	"cities":           "if(city = '', '', arrayStringConcat(arrayFilter(x -> x != '', [city, region, country]), ', '))",
+	"networks":         "if(asn = 0, '', concat('AS', toString(asn), ' ', as_org))",
+	"sources":          "utm_source",
+	"mediums":          "utm_medium",
+	"campaigns":        "utm_campaign",
+	"referrers":        "referer_domain",
+	"channels":         "channel",
+}
+
+// rollupDimensions are kept in the daily rollup, so their breakdowns cover
+// whole days and are not limited to the raw click retention.
+var rollupDimensions = map[Dimension]string{
+	"countries": "country",
+}
+
+type Query struct {
+	ID        domain.SnowflakeID
+	UserID    string
+	Dimension Dimension
+	From      time.Time
+	To        time.Time
+	Limit     int
+	// IncludeBots counts clicks classified as bots.
+	IncludeBots bool
+}
+
+type QueryResult struct {
+	ShortCode string    `json:"short_code"`
+	Dimension Dimension `json:"dimension"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Items     []Item    `json:"items"`
+}
+
+type Item = clickscope.Count
+
+type QueryHandler struct {
+	repo domain.URLRepository
+	ch   clickhouse.Conn
+}
+
+func NewQueryHandler(repo domain.URLRepository, ch clickhouse.Conn) *QueryHandler {
+	return &QueryHandler{repo: repo, ch: ch}
+}
+
+// Handle groups the link's clicks by the requested dimension, most clicked
+// first. Raw clicks are kept for 30 days, rollup dimensions for as long as
+// the rollup.
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResult, error) {
+	if !q.From.Before(q.To) {
+		return nil, errInvalidRange
+	}
+
+	// GetLifetime only finds the caller's own links, and the scope keeps out
+	// the clicks of other links that held the same short code.
+	link, err := h.repo.GetLifetime(ctx, q.ID, q.UserID)
+	if err != nil {
+		return nil, err
+	}
+	from, to := clickscope.Clamp(link, q.From, q.To)
+
+	res := &QueryResult{
+		ShortCode: link.ShortCode.String(),
+		Dimension: q.Dimension,
+		From:      from,
+		To:        to,
+		Items:     make([]Item, 0),
+	}
+	if !from.Before(to) {
+		return res, nil
+	}
+
+	scope := clickscope.Link(link)
+	if column, ok := rollupDimensions[q.Dimension]; ok {
+		res.Items, err = scope.DailyBreakdown(ctx, h.ch, column, from, to, q.IncludeBots, q.Limit)
+	} else {
+		res.Items, err = scope.Breakdown(ctx, h.ch, dimensions[q.Dimension], from, to, q.IncludeBots, q.Limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
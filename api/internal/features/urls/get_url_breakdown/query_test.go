@@ -0,0 +1,122 @@
+package geturlbreakdown
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	ingestclicks "github.com/SirNacou/refract/api/internal/features/clicks/ingest_clicks"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickhouse/clickhousetest"
+	"github.com/SirNacou/refract/api/internal/infrastructure/worker"
+)
+
+// Every dimension the endpoint accepts groups by an expression, and there
+// is no expression it cannot be asked for.
+func TestDimensionsMatchRequest(t *testing.T) {
+	field, _ := reflect.TypeFor[Request]().FieldByName("Dimension")
+	accepted := strings.Split(field.Tag.Get("enum"), ",")
+
+	for _, d := range accepted {
+		_, raw := dimensions[Dimension(d)]
+		_, rollup := rollupDimensions[Dimension(d)]
+		if raw == rollup {
+			t.Errorf("%s: in raw dimensions %t, in rollup dimensions %t, want exactly one", d, raw, rollup)
+		}
+	}
+	if n := len(dimensions) + len(rollupDimensions); n != len(accepted) {
+		t.Errorf("got %d dimensions, the request accepts %d", n, len(accepted))
+	}
+}
+
+type fakeRepo struct {
+	domain.URLRepository
+	link *domain.URLLifetime
+}
+
+func (r *fakeRepo) GetLifetime(_ context.Context, id domain.SnowflakeID, userID string) (*domain.URLLifetime, error) {
+	if r.link.ID != id || r.link.UserID != userID {
+		return nil, domain.ErrURLNotFound
+	}
+	return r.link, nil
+}
+
+const (
+	iPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	android = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36"
+	edge    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51"
+	crawler = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+)
+
+// Clicks go through the worker's enrichment, so the breakdowns group the
+// dimensions as they are stored.
+func TestHandleGroupsByDimension(t *testing.T) {
+	conn := clickhousetest.Open(t)
+	ctx := context.Background()
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2).Add(12 * time.Hour)
+	link := &domain.URLLifetime{URL: domain.URL{ID: 1001, ShortCode: "promo", UserID: "user-a", CreatedAt: day.AddDate(0, 0, -10)}}
+
+	clicks := []ingestclicks.Click{
+		{UserAgent: iPhone, IPAddress: "2001:db8::1"},
+		{UserAgent: iPhone, IPAddress: "2001:db8::2"},
+		{UserAgent: android, IPAddress: "2001:db8::3"},
+		{UserAgent: edge, IPAddress: "2001:db8::1"},
+		{UserAgent: crawler, IPAddress: "2001:db8::4"},
+	}
+	// The enricher has no GeoIP database here.
+	countries := []string{"DE", "DE", "FR", "DE", "US"}
+	enricher := worker.NewEnricher(nil, nil, nil, nil)
+	for i := range clicks {
+		enricher.Enrich(&clicks[i])
+		clicks[i].URLID = int64(link.ID)
+		clicks[i].UserID = link.UserID
+		clicks[i].ShortCode = link.ShortCode.String()
+		clicks[i].ClickedAt = day.Add(time.Duration(i) * time.Minute)
+		clicks[i].Country = countries[i]
+	}
+	if err := ingestclicks.NewCommandHandler(conn).Handle(ctx, &ingestclicks.Command{Clicks: clicks}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dimension   Dimension
+		includeBots bool
+		limit       int
+		want        []Item
+	}{
+		{"devices", false, 10, []Item{{Value: "mobile", Clicks: 3, UniqueIPs: 3}, {Value: "desktop", Clicks: 1, UniqueIPs: 1}}},
+		{"devices", true, 10, []Item{{Value: "mobile", Clicks: 3, UniqueIPs: 3}, {Value: "bot", Clicks: 1, UniqueIPs: 1}, {Value: "desktop", Clicks: 1, UniqueIPs: 1}}},
+		{"devices", false, 1, []Item{{Value: "mobile", Clicks: 3, UniqueIPs: 3}}},
+		{"browsers", false, 10, []Item{{Value: "Safari", Clicks: 2, UniqueIPs: 2}, {Value: "Chrome", Clicks: 1, UniqueIPs: 1}, {Value: "Edge", Clicks: 1, UniqueIPs: 1}}},
+		{"browsers", true, 10, []Item{{Value: "Safari", Clicks: 2, UniqueIPs: 2}, {Value: "Chrome", Clicks: 1, UniqueIPs: 1}, {Value: "Edge", Clicks: 1, UniqueIPs: 1}, {Value: "Googlebot", Clicks: 1, UniqueIPs: 1}}},
+		{"browser-versions", false, 10, []Item{{Value: "Safari 17.4", Clicks: 2, UniqueIPs: 2}, {Value: "Chrome 124.0.6367.82", Clicks: 1, UniqueIPs: 1}, {Value: "Edge 124.0.2478.51", Clicks: 1, UniqueIPs: 1}}},
+		{"os", false, 10, []Item{{Value: "iOS", Clicks: 2, UniqueIPs: 2}, {Value: "Android", Clicks: 1, UniqueIPs: 1}, {Value: "Windows", Clicks: 1, UniqueIPs: 1}}},
+		// Crawlers have no OS.
+		{"os", true, 10, []Item{{Value: "iOS", Clicks: 2, UniqueIPs: 2}, {Value: "", Clicks: 1, UniqueIPs: 1}, {Value: "Android", Clicks: 1, UniqueIPs: 1}, {Value: "Windows", Clicks: 1, UniqueIPs: 1}}},
+		{"os-versions", false, 10, []Item{{Value: "iOS 17.4", Clicks: 2, UniqueIPs: 2}, {Value: "Android 14", Clicks: 1, UniqueIPs: 1}, {Value: "Windows 10", Clicks: 1, UniqueIPs: 1}}},
+		// Countries come from the daily rollup.
+		{"countries", false, 10, []Item{{Value: "DE", Clicks: 3, UniqueIPs: 2}, {Value: "FR", Clicks: 1, UniqueIPs: 1}}},
+		{"countries", true, 10, []Item{{Value: "DE", Clicks: 3, UniqueIPs: 2}, {Value: "FR", Clicks: 1, UniqueIPs: 1}, {Value: "US", Clicks: 1, UniqueIPs: 1}}},
+	}
+
+	h := NewQueryHandler(&fakeRepo{link: link}, conn)
+	for _, tt := range tests {
+		res, err := h.Handle(ctx, &Query{
+			ID:          link.ID,
+			UserID:      link.UserID,
+			Dimension:   tt.dimension,
+			From:        day.Add(-6 * time.Hour),
+			To:          day.Add(6 * time.Hour),
+			Limit:       tt.limit,
+			IncludeBots: tt.includeBots,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.Items, tt.want) {
+			t.Errorf("%s, bots %t, limit %d: got %v, want %v", tt.dimension, tt.includeBots, tt.limit, res.Items, tt.want)
+		}
+	}
+}
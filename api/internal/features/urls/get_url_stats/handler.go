@@ -0,0 +1,74 @@
+package geturlstats
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Request struct {
+	ID       string    `path:"id"`
+	From     time.Time `query:"from" doc:"Start of the range. Defaults to 30 days before to."`
+	To       time.Time `query:"to" doc:"End of the range. Defaults to now."`
+	Interval string    `query:"interval" enum:"hour,day,week" default:"day"`
+
+	IncludeBots bool `query:"include_bots" doc:"Count clicks from bots, crawlers and link previews"`
+}
+
+type Response struct {
+	Body *QueryResult
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *Request) (*Response, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+
+	to := req.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -30)
+	}
+
+	res, err := h.query.Handle(ctx, &Query{
+		ID:          domain.SnowflakeID(id),
+		UserID:      userID,
+		From:        from,
+		To:          to,
+		Interval:    Interval(req.Interval),
+		IncludeBots: req.IncludeBots,
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+	if errors.Is(err, errInvalidRange) || errors.Is(err, errRangeTooLarge) {
+		return nil, huma.Error400BadRequest("Invalid time range", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to query stats", err)
+	}
+
+	return &Response{Body: res}, nil
+}
@@ -0,0 +1,205 @@
+package geturlstats
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickscope"
+)
+
+// maxBuckets caps the length of the time series.
+const maxBuckets = 1000
+
+var (
+	errInvalidRange  = errors.New("from must be before to")
+	errRangeTooLarge = errors.New("time range has too many buckets for the interval")
+)
+
+type Interval string
+
+const (
+	IntervalHour Interval = "hour"
+	IntervalDay  Interval = "day"
+	IntervalWeek Interval = "week"
+)
+
+func (i Interval) duration() time.Duration {
+	switch i {
+	case IntervalHour:
+		return time.Hour
+	case IntervalWeek:
+		return 7 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+type Query struct {
+	ID       domain.SnowflakeID
+	UserID   string
+	From     time.Time
+	To       time.Time
+	Interval Interval
+	// IncludeBots counts clicks classified as bots.
+	IncludeBots bool
+}
+
+type QueryResult struct {
+	ShortCode    string     `json:"short_code"`
+	From         time.Time  `json:"from"`
+	To           time.Time  `json:"to"`
+	Interval     Interval   `json:"interval"`
+	TotalClicks  uint64     `json:"total_clicks"`
+	UniqueIPs    uint64     `json:"unique_ips"`
+	FirstClickAt *time.Time `json:"first_click_at" doc:"Null when there are no clicks in the range"`
+	LastClickAt  *time.Time `json:"last_click_at" doc:"Null when there are no clicks in the range"`
+	Series       []Bucket   `json:"series"`
+}
+
+type Bucket struct {
+	Start     time.Time `json:"start" ch:"bucket"`
+	Clicks    uint64    `json:"clicks" ch:"clicks"`
+	UniqueIPs uint64    `json:"unique_ips" ch:"unique_ips"`
+}
+
+// Hourly stats come from the raw clicks, which are kept for 30 days. Daily
+// and weekly stats come from the daily rollup and cover whole days. Both
+// only read the clicks of the link, see [clickscope].
+const (
+	hourlyTotals = `
+		SELECT count(), uniq(ip_address), min(clicked_at), max(clicked_at)
+		FROM ` + clickscope.Clicks + `
+		WHERE clicked_at >= ? AND clicked_at < ?
+		{bot_filter}
+	`
+	dailyTotals = `
+		SELECT sumMerge({clicks}), uniqMerge({unique_ips}), minMerge(first_click_at), maxMerge(last_click_at)
+		FROM ` + clickscope.DailyStats + `
+		WHERE date >= toDate(?) AND date <= toDate(?)
+	`
+)
+
+// Placeholders in the queries pick human-only or all clicks. Only these
+// fixed strings are put into SQL.
+var (
+	humanOnly = strings.NewReplacer("{clicks}", "human_clicks", "{unique_ips}", "human_unique_ips", "{bot_filter}", "AND NOT is_bot")
+	allClicks = strings.NewReplacer("{clicks}", "clicks", "{unique_ips}", "unique_ips", "{bot_filter}", "")
+)
+
+// seriesQueries take from, to, from, to. WITH FILL adds empty
+// buckets so the series has no gaps.
+var seriesQueries = map[Interval]string{
+	IntervalHour: `
+		SELECT toStartOfHour(clicked_at) AS bucket, count() AS clicks, uniq(ip_address) AS unique_ips
+		FROM ` + clickscope.Clicks + `
+		WHERE clicked_at >= ? AND clicked_at < ?
+		{bot_filter}
+		GROUP BY bucket
+		ORDER BY bucket ASC WITH FILL
+			FROM toStartOfHour(toDateTime(?))
+			TO toDateTime(?)
+			STEP INTERVAL 1 HOUR
+	`,
+	IntervalDay: `
+		SELECT date AS bucket, sumMerge({clicks}) AS clicks, uniqMerge({unique_ips}) AS unique_ips
+		FROM ` + clickscope.DailyStats + `
+		WHERE date >= toDate(?) AND date <= toDate(?)
+		GROUP BY bucket
+		ORDER BY bucket ASC WITH FILL
+			FROM toDate(?)
+			TO toDate(?) + 1
+			STEP INTERVAL 1 DAY
+	`,
+	IntervalWeek: `
+		SELECT toMonday(date) AS bucket, sumMerge({clicks}) AS clicks, uniqMerge({unique_ips}) AS unique_ips
+		FROM ` + clickscope.DailyStats + `
+		WHERE date >= toDate(?) AND date <= toDate(?)
+		GROUP BY bucket
+		ORDER BY bucket ASC WITH FILL
+			FROM toMonday(toDate(?))
+			TO toDate(?) + 1
+			STEP INTERVAL 1 WEEK
+	`,
+}
+
+type QueryHandler struct {
+	repo domain.URLRepository
+	ch   clickhouse.Conn
+}
+
+func NewQueryHandler(repo domain.URLRepository, ch clickhouse.Conn) *QueryHandler {
+	return &QueryHandler{repo: repo, ch: ch}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, q *Query) (*QueryResult, error) {
+	if !q.From.Before(q.To) {
+		return nil, errInvalidRange
+	}
+	if q.To.Sub(q.From)/q.Interval.duration() >= maxBuckets {
+		return nil, errRangeTooLarge
+	}
+
+	// GetLifetime only finds the caller's own links, which keeps other
+	// users' stats private.
+	link, err := h.repo.GetLifetime(ctx, q.ID, q.UserID)
+	if err != nil {
+		return nil, err
+	}
+	from, to := clickscope.Clamp(link, q.From, q.To)
+
+	totals := dailyTotals
+	if q.Interval == IntervalHour {
+		totals = hourlyTotals
+	}
+	series := seriesQueries[q.Interval]
+
+	r := humanOnly
+	if q.IncludeBots {
+		r = allClicks
+	}
+	totals, series = r.Replace(totals), r.Replace(series)
+
+	res := &QueryResult{
+		ShortCode: link.ShortCode.String(),
+		From:      from,
+		To:        to,
+		Interval:  q.Interval,
+		Series:    make([]Bucket, 0),
+	}
+	if !from.Before(to) {
+		return res, nil
+	}
+
+	chCtx := clickscope.Link(link).Context(ctx)
+
+	var first, last time.Time
+	err = h.ch.QueryRow(chCtx, totals, from, to).
+		Scan(&res.TotalClicks, &res.UniqueIPs, &first, &last)
+	if err != nil {
+		return nil, err
+	}
+	if res.TotalClicks > 0 {
+		res.FirstClickAt = &first
+		res.LastClickAt = &last
+	}
+
+	rows, err := h.ch.Query(chCtx, series, from, to, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b Bucket
+		if err := rows.ScanStruct(&b); err != nil {
+			return nil, err
+		}
+		res.Series = append(res.Series, b)
+	}
+
+	return res, rows.Err()
+}
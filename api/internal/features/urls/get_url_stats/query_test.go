@@ -0,0 +1,181 @@
+package geturlstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/SirNacou/refract/api/internal/domain"
+	ingestclicks "github.com/SirNacou/refract/api/internal/features/clicks/ingest_clicks"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickhouse/clickhousetest"
+)
+
+type fakeRepo struct {
+	domain.URLRepository
+	link *domain.URLLifetime
+}
+
+func (r *fakeRepo) GetLifetime(_ context.Context, id domain.SnowflakeID, userID string) (*domain.URLLifetime, error) {
+	if r.link == nil || r.link.ID != id || r.link.UserID != userID {
+		return nil, domain.ErrURLNotFound
+	}
+	return r.link, nil
+}
+
+// The range is checked before the link is looked up. A range that passes
+// reaches the repository, which has no link here.
+func TestHandleChecksRange(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		interval Interval
+		to       time.Time
+		want     error
+	}{
+		{"empty", IntervalDay, from, errInvalidRange},
+		{"reversed", IntervalDay, from.Add(-time.Hour), errInvalidRange},
+		{"999 hours", IntervalHour, from.Add(999 * time.Hour), domain.ErrURLNotFound},
+		{"1000 hours", IntervalHour, from.Add(1000 * time.Hour), errRangeTooLarge},
+		{"1000 hours by day", IntervalDay, from.Add(1000 * time.Hour), domain.ErrURLNotFound},
+		{"1000 days", IntervalDay, from.AddDate(0, 0, 1000), errRangeTooLarge},
+		{"1000 days by week", IntervalWeek, from.AddDate(0, 0, 1000), domain.ErrURLNotFound},
+		{"1000 weeks", IntervalWeek, from.AddDate(0, 0, 7000), errRangeTooLarge},
+	}
+
+	h := NewQueryHandler(&fakeRepo{}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Handle(context.Background(), &Query{ID: 1001, UserID: "user-a", From: from, To: tt.to, Interval: tt.interval})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// newLink returns a link that was created before and still holds its
+// short code.
+func newLink(created time.Time) *domain.URLLifetime {
+	return &domain.URLLifetime{URL: domain.URL{ID: 1001, ShortCode: "promo", UserID: "user-a", CreatedAt: created}}
+}
+
+// ingest writes clicks on link the way the worker does, so they also reach
+// the daily rollup.
+func ingest(t *testing.T, conn driver.Conn, link *domain.URLLifetime, clicks ...ingestclicks.Click) {
+	t.Helper()
+
+	for i := range clicks {
+		clicks[i].URLID = int64(link.ID)
+		clicks[i].UserID = link.UserID
+		clicks[i].ShortCode = link.ShortCode.String()
+	}
+	if err := ingestclicks.NewCommandHandler(conn).Handle(context.Background(), &ingestclicks.Command{Clicks: clicks}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type want struct {
+	includeBots bool
+	series      []uint64
+	total       uint64
+	uniqueIPs   uint64
+	first, last time.Time
+}
+
+// assertStats runs q for link with and without bots. Buckets are compared
+// by their start as formatted by layout.
+func assertStats(t *testing.T, h *QueryHandler, link *domain.URLLifetime, q Query, layout string, starts []time.Time, wants ...want) {
+	t.Helper()
+
+	for _, w := range wants {
+		q.ID, q.UserID, q.IncludeBots = link.ID, link.UserID, w.includeBots
+
+		res, err := h.Handle(context.Background(), &q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.TotalClicks != w.total || res.UniqueIPs != w.uniqueIPs {
+			t.Errorf("bots %t: got %d clicks from %d addresses, want %d from %d", w.includeBots, res.TotalClicks, res.UniqueIPs, w.total, w.uniqueIPs)
+		}
+		if res.FirstClickAt == nil || !res.FirstClickAt.Equal(w.first) || res.LastClickAt == nil || !res.LastClickAt.Equal(w.last) {
+			t.Errorf("bots %t: got clicks from %v to %v, want %s to %s", w.includeBots, res.FirstClickAt, res.LastClickAt, w.first, w.last)
+		}
+		if len(res.Series) != len(w.series) {
+			t.Fatalf("bots %t: got %d buckets %v, want %d", w.includeBots, len(res.Series), res.Series, len(w.series))
+		}
+		for i, b := range res.Series {
+			start := b.Start.UTC().Format(layout)
+			if start != starts[i].Format(layout) || b.Clicks != w.series[i] {
+				t.Errorf("bots %t: bucket %d = %d clicks at %s, want %d at %s", w.includeBots, i, b.Clicks, start, w.series[i], starts[i].Format(layout))
+			}
+		}
+	}
+}
+
+func TestHandleHourlySeries(t *testing.T) {
+	conn := clickhousetest.Open(t)
+	h0 := time.Now().UTC().Truncate(time.Hour).Add(-48 * time.Hour)
+	link := newLink(h0.Add(-24 * time.Hour))
+
+	ingest(t, conn, link,
+		ingestclicks.Click{ClickedAt: h0.Add(10 * time.Minute), IPAddress: "2001:db8::1"},
+		ingestclicks.Click{ClickedAt: h0.Add(20 * time.Minute), IPAddress: "2001:db8::2"},
+		ingestclicks.Click{ClickedAt: h0.Add(2*time.Hour + 5*time.Minute), IPAddress: "2001:db8::1"},
+		ingestclicks.Click{ClickedAt: h0.Add(2*time.Hour + 30*time.Minute), IPAddress: "2001:db8::3", IsBot: true},
+		// After the range.
+		ingestclicks.Click{ClickedAt: h0.Add(4 * time.Hour), IPAddress: "2001:db8::1"},
+	)
+
+	// The hours without clicks are filled in.
+	starts := []time.Time{h0, h0.Add(time.Hour), h0.Add(2 * time.Hour), h0.Add(3 * time.Hour)}
+	q := Query{From: h0, To: h0.Add(4 * time.Hour), Interval: IntervalHour}
+	assertStats(t, NewQueryHandler(&fakeRepo{link: link}, conn), link, q, time.RFC3339, starts,
+		want{series: []uint64{2, 0, 1, 0}, total: 3, uniqueIPs: 2, first: h0.Add(10 * time.Minute), last: h0.Add(2*time.Hour + 5*time.Minute)},
+		want{includeBots: true, series: []uint64{2, 0, 2, 0}, total: 4, uniqueIPs: 3, first: h0.Add(10 * time.Minute), last: h0.Add(2*time.Hour + 30*time.Minute)},
+	)
+}
+
+// Daily and weekly stats come from the rollup and cover whole days, clicks
+// are at noon so the days do not depend on the server time zone.
+func TestHandleDailySeries(t *testing.T) {
+	conn := clickhousetest.Open(t)
+	d0 := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -10)
+	link := newLink(d0.AddDate(0, 0, -5))
+
+	ingest(t, conn, link,
+		ingestclicks.Click{ClickedAt: d0.Add(12 * time.Hour), IPAddress: "2001:db8::1"},
+		ingestclicks.Click{ClickedAt: d0.Add(13 * time.Hour), IPAddress: "2001:db8::2"},
+		ingestclicks.Click{ClickedAt: d0.AddDate(0, 0, 2).Add(10 * time.Hour), IPAddress: "2001:db8::3", IsBot: true},
+		ingestclicks.Click{ClickedAt: d0.AddDate(0, 0, 2).Add(12 * time.Hour), IPAddress: "2001:db8::1"},
+		// After the range.
+		ingestclicks.Click{ClickedAt: d0.AddDate(0, 0, 3).Add(12 * time.Hour), IPAddress: "2001:db8::1"},
+	)
+
+	starts := []time.Time{d0, d0.AddDate(0, 0, 1), d0.AddDate(0, 0, 2)}
+	q := Query{From: d0.Add(6 * time.Hour), To: d0.AddDate(0, 0, 2).Add(18 * time.Hour), Interval: IntervalDay}
+	assertStats(t, NewQueryHandler(&fakeRepo{link: link}, conn), link, q, time.DateOnly, starts,
+		want{series: []uint64{2, 0, 1}, total: 3, uniqueIPs: 2, first: d0.Add(12 * time.Hour), last: d0.AddDate(0, 0, 2).Add(12 * time.Hour)},
+		want{includeBots: true, series: []uint64{2, 0, 2}, total: 4, uniqueIPs: 3, first: d0.Add(12 * time.Hour), last: d0.AddDate(0, 0, 2).Add(12 * time.Hour)},
+	)
+}
+
+// Weeks start on Monday, also when the range does not.
+func TestHandleWeeklySeries(t *testing.T) {
+	conn := clickhousetest.Open(t)
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -24)
+	monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	link := newLink(monday.AddDate(0, 0, -7))
+
+	ingest(t, conn, link,
+		ingestclicks.Click{ClickedAt: monday.AddDate(0, 0, 2).Add(12 * time.Hour), IPAddress: "2001:db8::1"},
+		ingestclicks.Click{ClickedAt: monday.AddDate(0, 0, 16).Add(12 * time.Hour), IPAddress: "2001:db8::2"},
+	)
+
+	starts := []time.Time{monday, monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 14)}
+	q := Query{From: monday.AddDate(0, 0, 1).Add(6 * time.Hour), To: monday.AddDate(0, 0, 18).Add(18 * time.Hour), Interval: IntervalWeek}
+	assertStats(t, NewQueryHandler(&fakeRepo{link: link}, conn), link, q, time.DateOnly, starts,
+		want{series: []uint64{1, 0, 1}, total: 2, uniqueIPs: 2, first: monday.AddDate(0, 0, 2).Add(12 * time.Hour), last: monday.AddDate(0, 0, 16).Add(12 * time.Hour)},
+	)
+}
@@ -0,0 +1,64 @@
+package listdeletedurls
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+type Query struct {
+	userID string
+}
+
+type QueryResponse struct {
+	URLs []URL `json:"urls" default:"[]"`
+}
+
+type URL struct {
+	ID          string    `json:"id"`
+	OriginalURL string    `json:"original_url"`
+	ShortURL    string    `json:"short_url"`
+	Title       string    `json:"title"`
+	DeletedAt   time.Time `json:"deleted_at"`
+	PurgeAt     time.Time `json:"purge_at"`
+}
+
+type QueryHandler struct {
+	repo           domain.URLRepository
+	retention      time.Duration
+	defaultBaseURL string
+}
+
+func NewQueryHandler(repo domain.URLRepository, retention time.Duration, defaultBaseURL string) *QueryHandler {
+	return &QueryHandler{
+		repo:           repo,
+		retention:      retention,
+		defaultBaseURL: defaultBaseURL,
+	}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, req *Query) (*QueryResponse, error) {
+	urls, err := h.repo.ListDeletedByUser(ctx, req.userID, time.Now().Add(-h.retention))
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]URL, len(urls))
+	for i, u := range urls {
+		converted[i] = URL{
+			ID:          fmt.Sprint(u.ID.Int64()),
+			OriginalURL: u.OriginalURL,
+			ShortURL:    strings.Join([]string{h.defaultBaseURL, u.ShortCode.String()}, "/"),
+			Title:       u.Title,
+			DeletedAt:   *u.DeletedAt,
+			PurgeAt:     u.DeletedAt.Add(h.retention),
+		}
+	}
+
+	return &QueryResponse{
+		URLs: converted,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package listurls
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor turns the last URL of a page into an opaque token that clients
+// pass back unchanged to fetch the next page.
+func encodeCursor(u *domain.URL) string {
+	raw := strconv.FormatInt(u.CreatedAt.UnixMicro(), 10) + ":" + strconv.FormatInt(u.ID.Int64(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*domain.URLCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+
+	micros, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	snowflakeID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &domain.URLCursor{
+		CreatedAt: time.UnixMicro(micros),
+		ID:        domain.SnowflakeID(snowflakeID),
+	}, nil
+}
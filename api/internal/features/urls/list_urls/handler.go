@@ -2,12 +2,23 @@ package listurls
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
 	"github.com/danielgtaylor/huma/v2"
 )
 
-type Request struct{}
+type Request struct {
+	Cursor        string    `query:"cursor" doc:"Opaque cursor returned as next_cursor by the previous page"`
+	Limit         int       `query:"limit" minimum:"1" maximum:"100" default:"50"`
+	Sort          string    `query:"sort" enum:"newest,oldest" default:"newest"`
+	Status        string    `query:"status" enum:"active,disabled,expired"`
+	CreatedAfter  time.Time `query:"created_after" doc:"Only links created at or after this time"`
+	CreatedBefore time.Time `query:"created_before" doc:"Only links created before this time"`
+	ExpiresAfter  time.Time `query:"expires_after" doc:"Only links expiring at or after this time"`
+	ExpiresBefore time.Time `query:"expires_before" doc:"Only links expiring before this time"`
+}
 
 type Response struct {
 	Body *QueryResponse
@@ -32,10 +43,34 @@ func (h *Handler) Handle(ctx context.Context, req *Request) (*Response, error) {
 		return nil, huma.Error401Unauthorized("Unauthorized", err)
 	}
 
-	res, err := h.query.Handle(ctx, &Query{userID})
+	q := &Query{
+		userID:        userID,
+		cursor:        req.Cursor,
+		limit:         req.Limit,
+		sort:          req.Sort,
+		createdAfter:  optionalTime(req.CreatedAfter),
+		createdBefore: optionalTime(req.CreatedBefore),
+		expiresAfter:  optionalTime(req.ExpiresAfter),
+		expiresBefore: optionalTime(req.ExpiresBefore),
+	}
+	if req.Status != "" {
+		q.status = &req.Status
+	}
+
+	res, err := h.query.Handle(ctx, q)
+	if errors.Is(err, errInvalidCursor) {
+		return nil, huma.Error400BadRequest("Invalid cursor", err)
+	}
 	if err != nil {
 		return nil, huma.Error400BadRequest("Failed to query URLs", err)
 	}
 
 	return &Response{Body: res}, nil
 }
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
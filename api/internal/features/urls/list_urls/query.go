@@ -3,69 +3,166 @@ package listurls
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
 )
 
 type Query struct {
-	userID string
+	userID        string
+	cursor        string
+	limit         int
+	sort          string
+	status        *domain.Status
+	createdAfter  *time.Time
+	createdBefore *time.Time
+	expiresAfter  *time.Time
+	expiresBefore *time.Time
 }
 
 type QueryResponse struct {
-	URLs []URL `json:"urls" default:"[]"`
+	URLs       []URL   `json:"urls" default:"[]"`
+	NextCursor *string `json:"next_cursor" doc:"Pass as cursor to fetch the next page. Null on the last page."`
 }
 
 type URL struct {
-	ID          string        `json:"id"`
-	OriginalURL string        `json:"original_url"`
-	ShortURL    string        `json:"short_url"`
-	Title       string        `json:"title"`
-	Notes       string        `json:"notes"`
-	UserID      string        `json:"user_id"`
-	ExpiresAt   *time.Time    `json:"expires_at"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
-	Status      domain.Status `json:"status"`
+	ID                string                                  `json:"id"`
+	OriginalURL       string                                  `json:"original_url"`
+	ShortURL          string                                  `json:"short_url"`
+	Title             string                                  `json:"title"`
+	Notes             string                                  `json:"notes"`
+	UserID            string                                  `json:"user_id"`
+	ExpiresAt         *time.Time                              `json:"expires_at"`
+	CreatedAt         time.Time                               `json:"created_at"`
+	UpdatedAt         time.Time                               `json:"updated_at"`
+	Status            domain.Status                           `json:"status"`
+	RedirectStatus    int                                     `json:"redirect_status" doc:"0 means the server default"`
+	ForwardQuery      bool                                    `json:"forward_query"`
+	ForwardPath       bool                                    `json:"forward_path"`
+	PasswordProtected bool                                    `json:"password_protected"`
+	MaxClicks         *int                                    `json:"max_clicks" doc:"Null when the link has no click cap"`
+	RemainingClicks   *int                                    `json:"remaining_clicks" doc:"Null when the link has no click cap"`
+	ActiveFrom        *time.Time                              `json:"active_from"`
+	FallbackURL       string                                  `json:"fallback_url"`
+	GeoTargets        map[string]string                       `json:"geo_targets"`
+	DeviceTargets     map[domain.Platform]domain.DeviceTarget `json:"device_targets"`
+	Variants          []domain.Variant                        `json:"variants"`
+	StickyVariants    bool                                    `json:"sticky_variants"`
 }
 
 type QueryHandler struct {
 	repo           domain.URLRepository
+	valkey         valkeyaside.CacheAsideClient
 	defaultBaseURL string
+	clickCountKey  string
 }
 
-func NewQueryHandler(repo domain.URLRepository, defaultBaseURL string) *QueryHandler {
+func NewQueryHandler(repo domain.URLRepository, valkey valkeyaside.CacheAsideClient, defaultBaseURL, clickCountKey string) *QueryHandler {
 	return &QueryHandler{
 		repo:           repo,
+		valkey:         valkey,
 		defaultBaseURL: defaultBaseURL,
+		clickCountKey:  clickCountKey,
 	}
 }
 
 func (h *QueryHandler) Handle(ctx context.Context, req *Query) (*QueryResponse, error) {
-	urls, err := h.repo.ListByUser(ctx, req.userID)
+	filter := domain.URLFilter{
+		UserID:        req.userID,
+		Status:        req.status,
+		CreatedAfter:  req.createdAfter,
+		CreatedBefore: req.createdBefore,
+		ExpiresAfter:  req.expiresAfter,
+		ExpiresBefore: req.expiresBefore,
+		Ascending:     req.sort == SortOldest,
+		// Fetch one extra row to know whether another page exists.
+		Limit: req.limit + 1,
+	}
+
+	if req.cursor != "" {
+		after, err := decodeCursor(req.cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	urls, err := h.repo.ListByUser(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
+	var nextCursor *string
+	if len(urls) > req.limit {
+		urls = urls[:req.limit]
+		c := encodeCursor(&urls[len(urls)-1])
+		nextCursor = &c
+	}
+
+	clicks := h.clickCounts(ctx, urls)
+
 	converted := make([]URL, len(urls))
 	for i, u := range urls {
 		sURL := strings.Join([]string{h.defaultBaseURL, u.ShortCode.String()}, "/")
 		converted[i] = URL{
-			ID:          fmt.Sprint(u.ID.Int64()),
-			OriginalURL: u.OriginalURL,
-			ShortURL:    sURL,
-			Title:       u.Title,
-			Notes:       u.Notes,
-			UserID:      u.UserID,
-			ExpiresAt:   u.ExpiresAt,
-			CreatedAt:   u.CreatedAt,
-			UpdatedAt:   u.UpdatedAt,
-			Status:      u.Status,
+			ID:                fmt.Sprint(u.ID.Int64()),
+			OriginalURL:       u.OriginalURL,
+			ShortURL:          sURL,
+			Title:             u.Title,
+			Notes:             u.Notes,
+			UserID:            u.UserID,
+			ExpiresAt:         u.ExpiresAt,
+			CreatedAt:         u.CreatedAt,
+			UpdatedAt:         u.UpdatedAt,
+			Status:            u.Status,
+			RedirectStatus:    u.RedirectStatus,
+			ForwardQuery:      u.ForwardQuery,
+			ForwardPath:       u.ForwardPath,
+			PasswordProtected: u.PasswordProtected(),
+			ActiveFrom:        u.ActiveFrom,
+			FallbackURL:       u.FallbackURL,
+			GeoTargets:        u.GeoTargets,
+			DeviceTargets:     u.DeviceTargets,
+			Variants:          u.Variants,
+			StickyVariants:    u.StickyVariants,
+		}
+		if u.MaxClicks > 0 {
+			remaining := max(u.MaxClicks-int(clicks[u.ID]), 0)
+			converted[i].MaxClicks = &u.MaxClicks
+			converted[i].RemainingClicks = &remaining
 		}
 	}
 
 	return &QueryResponse{
-		URLs: converted,
+		URLs:       converted,
+		NextCursor: nextCursor,
 	}, nil
 }
+
+// clickCounts loads the click counters of capped links. A Valkey failure is
+// logged and reported as no clicks rather than failing the listing.
+func (h *QueryHandler) clickCounts(ctx context.Context, urls []domain.URL) map[domain.SnowflakeID]int64 {
+	var capped []domain.SnowflakeID
+	for _, u := range urls {
+		if u.MaxClicks > 0 {
+			capped = append(capped, u.ID)
+		}
+	}
+
+	counts, err := cache.ClickCounts(ctx, h.valkey.Client(), h.clickCountKey, capped)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load click counts", "error", err)
+		return nil
+	}
+
+	return counts
+}
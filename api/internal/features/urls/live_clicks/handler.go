@@ -0,0 +1,258 @@
+package liveclicks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/SirNacou/refract/api/internal/infrastructure/livestream"
+	"github.com/SirNacou/refract/api/internal/infrastructure/publisher"
+	"github.com/SirNacou/refract/api/internal/infrastructure/referrer"
+	"github.com/SirNacou/refract/api/internal/infrastructure/useragent"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+const (
+	// bufferSize is how many clicks a slow client may fall behind before it
+	// starts missing them.
+	bufferSize      = 256
+	pingInterval    = 15 * time.Second
+	writeTimeout    = 5 * time.Second
+	refreshInterval = time.Minute
+)
+
+type LinkRequest struct {
+	ID string `path:"id"`
+}
+
+type UserRequest struct {
+}
+
+// ClickEvent is sent as a "click" event. Clicks are not yet enriched by the
+// worker, so device and referrer are derived on the fly and there is no
+// location or bot flag.
+type ClickEvent struct {
+	ShortCode   string    `json:"short_code"`
+	ClickedAt   time.Time `json:"clicked_at"`
+	IPAddress   string    `json:"ip_address"`
+	Device      string    `json:"device"`
+	Browser     string    `json:"browser"`
+	OS          string    `json:"os"`
+	Referrer    string    `json:"referrer"`
+	Variant     string    `json:"variant,omitempty"`
+	UTMSource   string    `json:"utm_source,omitempty"`
+	UTMCampaign string    `json:"utm_campaign,omitempty"`
+}
+
+// LaggedEvent is sent as a "lagged" event before the next event when the
+// client read too slowly and missed clicks.
+type LaggedEvent struct {
+	Dropped int64 `json:"dropped"`
+}
+
+// PingEvent is sent as a "ping" event when there were no clicks for a while
+// so proxies keep the connection open.
+type PingEvent struct {
+	Time time.Time `json:"time"`
+}
+
+type Handler struct {
+	repo domain.URLRepository
+	hub  *livestream.Hub
+}
+
+func NewHandler(repo domain.URLRepository, hub *livestream.Hub) *Handler {
+	return &Handler{repo: repo, hub: hub}
+}
+
+// HandleLink streams the clicks of one of the caller's links while it is
+// active.
+func (h *Handler) HandleLink(ctx context.Context, req *LinkRequest) (*huma.StreamResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+
+	owned := &ownedLinks{load: func(ctx context.Context) ([]domain.URLLifetime, error) {
+		link, err := h.repo.GetLifetime(ctx, domain.SnowflakeID(id), userID)
+		if errors.Is(err, domain.ErrURLNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []domain.URLLifetime{*link}, nil
+	}}
+	if err := owned.refresh(ctx); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to get URL", err)
+	}
+	if len(*owned.links.Load()) == 0 {
+		return nil, huma.Error404NotFound("URL not found or not active")
+	}
+
+	return h.stream(owned.contains, owned.refresh), nil
+}
+
+// HandleUser streams the clicks of all the caller's active links. Links
+// created or deactivated after the stream starts are picked up within
+// refreshInterval.
+func (h *Handler) HandleUser(ctx context.Context, req *UserRequest) (*huma.StreamResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	owned := &ownedLinks{load: func(ctx context.Context) ([]domain.URLLifetime, error) {
+		return h.repo.ListLifetimesByUser(ctx, userID)
+	}}
+	if err := owned.refresh(ctx); err != nil {
+		return nil, huma.Error500InternalServerError("Failed to list URLs", err)
+	}
+
+	return h.stream(owned.contains, owned.refresh), nil
+}
+
+func (h *Handler) stream(filter func(*publisher.ClicksPublisherRequest) bool, refresh func(context.Context) error) *huma.StreamResponse {
+	return &huma.StreamResponse{
+		Body: func(hctx huma.Context) {
+			ctx := hctx.Context()
+			hctx.SetHeader("Content-Type", "text/event-stream")
+			hctx.SetHeader("Cache-Control", "no-cache")
+			hctx.SetHeader("X-Accel-Buffering", "no")
+
+			w, ok := hctx.BodyWriter().(http.ResponseWriter)
+			if !ok {
+				slog.ErrorContext(ctx, "Live clicks need an http.ResponseWriter")
+				return
+			}
+			rc := http.NewResponseController(w)
+
+			write := func(event string, data any) error {
+				payload, err := json.Marshal(data)
+				if err != nil {
+					return err
+				}
+				// The server WriteTimeout would otherwise end the stream.
+				if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+					return err
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+					return err
+				}
+				return rc.Flush()
+			}
+
+			sub := h.hub.Subscribe(bufferSize, filter)
+			defer h.hub.Unsubscribe(sub)
+
+			ping := time.NewTicker(pingInterval)
+			defer ping.Stop()
+			refreshTicker := time.NewTicker(refreshInterval)
+			defer refreshTicker.Stop()
+
+			if err := write("ping", PingEvent{Time: time.Now()}); err != nil {
+				return
+			}
+
+			for {
+				var err error
+				select {
+				case <-ctx.Done():
+					return
+				case click := <-sub.Events():
+					if n := sub.TakeDropped(); n > 0 {
+						err = write("lagged", LaggedEvent{Dropped: n})
+					}
+					if err == nil {
+						err = write("click", toEvent(click))
+					}
+					ping.Reset(pingInterval)
+				case <-ping.C:
+					if n := sub.TakeDropped(); n > 0 {
+						err = write("lagged", LaggedEvent{Dropped: n})
+					} else {
+						err = write("ping", PingEvent{Time: time.Now()})
+					}
+				case <-refreshTicker.C:
+					if refresh != nil {
+						if err := refresh(ctx); err != nil {
+							slog.ErrorContext(ctx, "Failed to refresh live clicks filter", "error", err)
+						}
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		},
+	}
+}
+
+func toEvent(c *publisher.ClicksPublisherRequest) ClickEvent {
+	ua := useragent.Parse(c.UserAgent)
+	return ClickEvent{
+		ShortCode:   c.ShortCode,
+		ClickedAt:   c.ClickedAt,
+		IPAddress:   c.IPAddress,
+		Device:      ua.DeviceType,
+		Browser:     ua.Browser,
+		OS:          ua.OS,
+		Referrer:    referrer.Domain(c.Referer),
+		Variant:     c.Variant,
+		UTMSource:   c.UTMSource,
+		UTMCampaign: c.UTMCampaign,
+	}
+}
+
+// ownedLinks is the set of a user's active links, which hold their short
+// code and redirect. It is read on the hub goroutine and replaced by
+// refresh.
+type ownedLinks struct {
+	load  func(ctx context.Context) ([]domain.URLLifetime, error)
+	links atomic.Pointer[[]domain.URLLifetime]
+}
+
+func (o *ownedLinks) refresh(ctx context.Context) error {
+	links, err := o.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	active := make([]domain.URLLifetime, 0, len(links))
+	for _, l := range links {
+		if l.EndedAt == nil && l.IsActive(now) {
+			active = append(active, l)
+		}
+	}
+	o.links.Store(&active)
+	return nil
+}
+
+// contains reports whether c was made on one of the links. Active links hold
+// their short code, so clicks published without the link ID are matched by
+// it.
+func (o *ownedLinks) contains(c *publisher.ClicksPublisherRequest) bool {
+	for _, l := range *o.links.Load() {
+		if c.URLID == 0 && l.ShortCode.String() == c.ShortCode {
+			return true
+		}
+		if c.URLID != 0 && l.ID == domain.SnowflakeID(c.URLID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package liveclicks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/publisher"
+)
+
+func TestOwnedLinksOnlyActive(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	link := func(id domain.SnowflakeID, code domain.ShortCode, status domain.Status) domain.URLLifetime {
+		return domain.URLLifetime{URL: domain.URL{
+			ID:        id,
+			ShortCode: code,
+			Status:    status,
+			CreatedAt: now.Add(-24 * time.Hour),
+		}}
+	}
+
+	active := link(1, "live", domain.Active)
+	disabled := link(2, "off", domain.Disabled)
+	expired := link(3, "old", domain.Active)
+	expired.ExpiresAt = &past
+	scheduled := link(4, "soon", domain.Active)
+	scheduled.ActiveFrom = &future
+	reused := link(5, "taken", domain.Active)
+	reused.EndedAt = &past
+
+	owned := &ownedLinks{load: func(context.Context) ([]domain.URLLifetime, error) {
+		return []domain.URLLifetime{active, disabled, expired, scheduled, reused}, nil
+	}}
+	if err := owned.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		click publisher.ClicksPublisherRequest
+		want  bool
+	}{
+		{"active link", publisher.ClicksPublisherRequest{URLID: 1, ShortCode: "live", ClickedAt: now}, true},
+		{"active link without url_id", publisher.ClicksPublisherRequest{ShortCode: "live", ClickedAt: now}, true},
+		{"disabled link", publisher.ClicksPublisherRequest{URLID: 2, ShortCode: "off", ClickedAt: now}, false},
+		{"expired link", publisher.ClicksPublisherRequest{URLID: 3, ShortCode: "old", ClickedAt: now}, false},
+		{"scheduled link", publisher.ClicksPublisherRequest{URLID: 4, ShortCode: "soon", ClickedAt: now}, false},
+		{"alias reused by another link", publisher.ClicksPublisherRequest{URLID: 99, ShortCode: "taken", ClickedAt: now}, false},
+		{"alias reused, without url_id", publisher.ClicksPublisherRequest{ShortCode: "taken", ClickedAt: now}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := owned.contains(&tt.click); got != tt.want {
+				t.Errorf("contains = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,19 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/SirNacou/refract/api/internal/config"
 	"github.com/SirNacou/refract/api/internal/domain"
+	deleteurl "github.com/SirNacou/refract/api/internal/features/urls/delete_url"
 	getdashboard "github.com/SirNacou/refract/api/internal/features/urls/get_dashboard"
+	getdashboardbreakdown "github.com/SirNacou/refract/api/internal/features/urls/get_dashboard_breakdown"
+	geturlbreakdown "github.com/SirNacou/refract/api/internal/features/urls/get_url_breakdown"
+	geturlstats "github.com/SirNacou/refract/api/internal/features/urls/get_url_stats"
+	listdeletedurls "github.com/SirNacou/refract/api/internal/features/urls/list_deleted_urls"
 	listurls "github.com/SirNacou/refract/api/internal/features/urls/list_urls"
+	liveclicks "github.com/SirNacou/refract/api/internal/features/urls/live_clicks"
+	restoreurl "github.com/SirNacou/refract/api/internal/features/urls/restore_url"
+	searchurls "github.com/SirNacou/refract/api/internal/features/urls/search_urls"
 	shortenurl "github.com/SirNacou/refract/api/internal/features/urls/shorten_url"
+	updateurl "github.com/SirNacou/refract/api/internal/features/urls/update_url"
+	"github.com/SirNacou/refract/api/internal/infrastructure/livestream"
 	"github.com/SirNacou/refract/api/internal/infrastructure/persistence"
 	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
 	"github.com/danielgtaylor/huma/v2"
@@ -16,16 +26,23 @@ import (
 )
 
 type Module struct {
-	repo   domain.URLRepository
-	valkey valkeyaside.CacheAsideClient
-	ch     clickhouse.Conn
-	cfg    *config.Config
+	repo     domain.URLRepository
+	settings domain.UserSettingsRepository
+	chURLs   *repository.ClickHouseURLRepository
+	valkey   valkeyaside.CacheAsideClient
+	ch       clickhouse.Conn
+	live     *livestream.Hub
+	cfg      *config.Config
 }
 
 func NewModule(db *persistence.DB, valkey valkeyaside.CacheAsideClient, clickhouse clickhouse.Conn, cfg *config.Config) *Module {
 	repo := repository.NewPostgresURLRepository(db.Querier)
+	settings := repository.NewPostgresUserSettingsRepository(db.Querier)
+	chURLs := repository.NewClickHouseURLRepository(clickhouse)
 
-	return &Module{repo, valkey, clickhouse, cfg}
+	live := livestream.NewHub(valkey.Client(), cfg.Valkey.ClicksStreamKey)
+
+	return &Module{repo, settings, chURLs, valkey, clickhouse, live, cfg}
 }
 
 func (m *Module) RegisterRoutes(api huma.API) error {
@@ -36,13 +53,44 @@ func (m *Module) RegisterRoutes(api huma.API) error {
 		OperationID: "list-urls",
 		Method:      http.MethodGet,
 		Path:        "/",
-	}, listurls.NewHandler(listurls.NewQueryHandler(m.repo, m.cfg.DefaultBaseURL)).Handle)
+	}, listurls.NewHandler(listurls.NewQueryHandler(m.repo, m.valkey, m.cfg.DefaultBaseURL, m.cfg.Valkey.ClickCountKey)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "search-urls",
+		Method:      http.MethodGet,
+		Path:        "/search",
+	}, searchurls.NewHandler(searchurls.NewQueryHandler(m.repo, m.cfg.DefaultBaseURL)).Handle)
 
 	huma.Register(grp, huma.Operation{
 		OperationID: "shorten-url",
 		Method:      http.MethodPost,
 		Path:        "/",
-	}, shortenurl.NewHandler(shortenurl.NewCommandHandler(m.repo, m.valkey, m.ch, m.cfg.DefaultBaseURL, m.cfg.Valkey.RedirectKey)).Handle)
+	}, shortenurl.NewHandler(shortenurl.NewCommandHandler(m.repo, m.settings, m.valkey, m.chURLs, m.cfg.DefaultBaseURL, m.cfg.Valkey.RedirectKey)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "update-url",
+		Method:      http.MethodPatch,
+		Path:        "/{id}",
+	}, updateurl.NewHandler(updateurl.NewCommandHandler(m.repo, m.valkey, m.chURLs, m.cfg.DefaultBaseURL, m.cfg.Valkey.RedirectKey)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID:   "delete-url",
+		Method:        http.MethodDelete,
+		Path:          "/{id}",
+		DefaultStatus: http.StatusNoContent,
+	}, deleteurl.NewHandler(deleteurl.NewCommandHandler(m.repo, m.valkey, m.chURLs, m.cfg.Valkey.RedirectKey)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "list-deleted-urls",
+		Method:      http.MethodGet,
+		Path:        "/trash",
+	}, listdeletedurls.NewHandler(listdeletedurls.NewQueryHandler(m.repo, m.cfg.TrashRetention, m.cfg.DefaultBaseURL)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "restore-url",
+		Method:      http.MethodPost,
+		Path:        "/{id}/restore",
+	}, restoreurl.NewHandler(restoreurl.NewCommandHandler(m.repo, m.valkey, m.chURLs, m.cfg.TrashRetention, m.cfg.DefaultBaseURL, m.cfg.Valkey.RedirectKey)).Handle)
 
 	huma.Register(grp, huma.Operation{
 		OperationID: "dashboard",
@@ -50,5 +98,45 @@ func (m *Module) RegisterRoutes(api huma.API) error {
 		Path:        "/dashboard",
 	}, getdashboard.NewHandler(getdashboard.NewQueryHandler(m.repo, m.ch, m.cfg.DefaultBaseURL)).Handle)
 
+	huma.Register(grp, huma.Operation{
+		OperationID: "get-dashboard-breakdown",
+		Method:      http.MethodGet,
+		Path:        "/dashboard/{dimension}",
+	}, getdashboardbreakdown.NewHandler(getdashboardbreakdown.NewQueryHandler(m.ch)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "get-url-stats",
+		Method:      http.MethodGet,
+		Path:        "/{id}/stats",
+	}, geturlstats.NewHandler(geturlstats.NewQueryHandler(m.repo, m.ch)).Handle)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "get-url-breakdown",
+		Method:      http.MethodGet,
+		Path:        "/{id}/stats/{dimension}",
+	}, geturlbreakdown.NewHandler(geturlbreakdown.NewQueryHandler(m.repo, m.ch)).Handle)
+
+	live := liveclicks.NewHandler(m.repo, m.live)
+	liveResponses := map[string]*huma.Response{
+		"200": {
+			Description: "Server-sent click, lagged and ping events",
+			Content:     map[string]*huma.MediaType{"text/event-stream": {}},
+		},
+	}
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "live-clicks",
+		Method:      http.MethodGet,
+		Path:        "/live",
+		Responses:   liveResponses,
+	}, live.HandleUser)
+
+	huma.Register(grp, huma.Operation{
+		OperationID: "live-url-clicks",
+		Method:      http.MethodGet,
+		Path:        "/{id}/live",
+		Responses:   liveResponses,
+	}, live.HandleLink)
+
 	return nil
 }
@@ -0,0 +1,36 @@
+package purgedeletedurls
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+// CommandHandler hard-deletes links that have been in the trash for longer
+// than the retention period.
+type CommandHandler struct {
+	repo      domain.URLRepository
+	retention time.Duration
+}
+
+func NewCommandHandler(repo domain.URLRepository, retention time.Duration) *CommandHandler {
+	return &CommandHandler{
+		repo:      repo,
+		retention: retention,
+	}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context) error {
+	purged, err := h.repo.PurgeDeleted(ctx, time.Now().Add(-h.retention))
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		slog.InfoContext(ctx, "Purged deleted URLs", "count", purged)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package redirect
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+)
+
+// claimClick counts a redirect against the click cap of link id and reports
+// whether it may go through. INCR makes concurrent visitors race on the
+// counter rather than on the cached entry, so a one-time link redirects once.
+// Only the click that reaches the cap retires the link.
+func (h *RedirectHandler) claimClick(ctx context.Context, id domain.SnowflakeID, shortCode string, maxClicks int) (bool, error) {
+	key := cache.ClickCountKey(h.clickCountKey, id)
+	n, err := h.valkey.Client().Do(ctx, h.valkey.Client().B().Incr().Key(key).Build()).AsInt64()
+	if err != nil {
+		return false, err
+	}
+
+	if n == int64(maxClicks) {
+		go h.exhaust(id, shortCode)
+	}
+
+	return n <= int64(maxClicks), nil
+}
+
+// exhaust retires a link that used up its clicks so it stops resolving once
+// the cached redirect is gone, and records the expired status in ClickHouse.
+func (h *RedirectHandler) exhaust(id domain.SnowflakeID, shortCode string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	u, err := h.repo.Exhaust(ctx, id)
+	if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+		slog.ErrorContext(ctx, "Failed to expire link after its last click", "short_code", shortCode, "error", err)
+	}
+
+	if err == nil {
+		err = h.chURLs.SaveCurrent(ctx, h.repo, u)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to update URL in ClickHouse", "short_code", shortCode, "error", err)
+		}
+	}
+
+	key := cache.RedirectKey(h.redirectKey, shortCode)
+	err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(key).Build()).Error()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate cached short code", "short_code", shortCode, "error", err)
+	}
+}
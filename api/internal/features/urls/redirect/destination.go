@@ -0,0 +1,68 @@
+package redirect
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+)
+
+var errPathNotForwarded = errors.New("link does not forward extra path segments")
+
+// buildDestination applies the link's passthrough options to base, the
+// destination picked for the visitor.
+//
+// Path: when ForwardPath is set, suffix (everything after /{shortCode}/) is
+// appended to the destination path. Links without ForwardPath only match the
+// bare short code.
+//
+// Query: when ForwardQuery is set, the visitor's parameters are appended to
+// the destination query. Parameters already present on the destination win:
+// an incoming key that the destination defines is dropped entirely, so the
+// link owner's values (e.g. a fixed utm_campaign) cannot be overridden.
+// Repeated incoming keys keep all of their values in order.
+func buildDestination(base string, entry *cache.RedirectEntry, suffix string, incoming url.Values) (string, error) {
+	if suffix == "" && (!entry.ForwardQuery || len(incoming) == 0) {
+		return base, nil
+	}
+
+	if suffix != "" && !entry.ForwardPath {
+		return "", errPathNotForwarded
+	}
+
+	dest, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	if suffix != "" {
+		// Clean against a rooted path so "../" cannot climb above the
+		// destination path.
+		cleaned := strings.TrimPrefix(path.Clean("/"+suffix), "/")
+		if cleaned != "" {
+			dest = dest.JoinPath(cleaned)
+		}
+	}
+
+	if entry.ForwardQuery && len(incoming) > 0 {
+		own := dest.Query()
+		extra := url.Values{}
+		for k, vs := range incoming {
+			if _, ok := own[k]; ok {
+				continue
+			}
+			extra[k] = vs
+		}
+
+		if len(extra) > 0 {
+			if dest.RawQuery != "" {
+				dest.RawQuery += "&"
+			}
+			dest.RawQuery += extra.Encode()
+		}
+	}
+
+	return dest.String(), nil
+}
@@ -2,33 +2,67 @@ package redirect
 
 import (
 	"context"
-	"fmt"
+	"crypto/rand"
+	"errors"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/SirNacou/refract/api/internal/config"
 	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/botdetect"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/geoip"
 	"github.com/SirNacou/refract/api/internal/infrastructure/publisher"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
 	"github.com/go-chi/chi/v5"
 	"github.com/valkey-io/valkey-go/valkeyaside"
 )
 
 type RedirectHandler struct {
 	repo           domain.URLRepository
+	chURLs         *repository.ClickHouseURLRepository
 	valkey         valkeyaside.CacheAsideClient
 	clickPublisher *publisher.ClicksPublisher
 	redirectKey    string
+	defaultStatus  int
+	clickCountKey  string
+	geo            *geoip.Reader
+
+	cookieSecret  []byte
+	cookieTTL     time.Duration
+	attemptsKey   string
+	maxAttempts   int
+	attemptWindow time.Duration
 }
 
-func NewRedirectHandler(valkey valkeyaside.CacheAsideClient, repo domain.URLRepository, publisher *publisher.ClicksPublisher, cfg *config.Config) *RedirectHandler {
+// NewRedirectHandler creates the handler. geo may be nil, in which case geo
+// targets are ignored.
+func NewRedirectHandler(valkey valkeyaside.CacheAsideClient, repo domain.URLRepository, chURLs *repository.ClickHouseURLRepository, publisher *publisher.ClicksPublisher, geo *geoip.Reader, cfg *config.Config) *RedirectHandler {
+	secret := []byte(cfg.LinkPassword.CookieSecret)
+	if len(secret) == 0 {
+		slog.Warn("LINK_PASSWORD_COOKIE_SECRET is not set, unlock cookies will not survive a restart")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
 	return &RedirectHandler{
 		valkey:         valkey,
 		repo:           repo,
+		chURLs:         chURLs,
 		clickPublisher: publisher,
 		redirectKey:    cfg.Valkey.RedirectKey,
+		defaultStatus:  cfg.DefaultRedirectStatus,
+		clickCountKey:  cfg.Valkey.ClickCountKey,
+		geo:            geo,
+		cookieSecret:   secret,
+		cookieTTL:      cfg.LinkPassword.CookieTTL,
+		attemptsKey:    cfg.Valkey.PasswordAttemptsKey,
+		maxAttempts:    cfg.LinkPassword.MaxAttempts,
+		attemptWindow:  cfg.LinkPassword.AttemptWindow,
 	}
 }
 
@@ -36,56 +70,135 @@ func (h *RedirectHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	shortCode := chi.URLParam(r, "shortCode")
 	slog.Info("Handling redirect", "short_code", shortCode)
 
-	key := strings.Replace(h.redirectKey, "{short_code}", shortCode, 1)
-	url, err := h.valkey.Get(r.Context(), time.Minute, key, func(ctx context.Context, key string) (val string, err error) {
-		url, err := h.repo.GetActiveURLByShortCode(ctx, domain.ShortCode(shortCode))
-		if err != nil {
-			return "", err
-		}
+	entry, err := h.resolve(r.Context(), shortCode)
+	if errors.Is(err, domain.ErrURLNotFound) {
+		WriteNotFoundPage(w)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to resolve short code", "short_code", shortCode, "error", err)
+		WriteUnavailablePage(w)
+		return
+	}
 
-		maxTTL := time.Hour * 24 * 365
-		if url.ExpiresAt != nil {
-			ttl := time.Until(*url.ExpiresAt)
-			if ttl < maxTTL {
-				maxTTL = ttl
-			}
-		}
-		valkeyaside.OverrideCacheTTL(ctx, maxTTL)
+	if state := entryState(entry, time.Now()); state != stateActive {
+		writeInactive(w, r, entry, state)
+		return
+	}
 
-		return url.OriginalURL, nil
-	})
+	if entry.PasswordVersion != "" && !h.unlocked(r, shortCode, entry.PasswordVersion) {
+		WritePasswordPage(w, http.StatusUnauthorized, "")
+		return
+	}
 
+	target := h.selectTarget(w, r, shortCode, entry)
+	destination, err := buildDestination(target.URL, entry, chi.URLParam(r, "*"), r.URL.Query())
 	if err != nil {
 		WriteNotFoundPage(w)
 		return
 	}
 
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		host = r.RemoteAddr
+	// Unfurlers fetch a link as soon as it is shared, a preview must not
+	// use up a one-time link before anyone opened it.
+	if entry.MaxClicks > 0 && !botdetect.IsPreviewUserAgent(r.UserAgent()) {
+		allowed, err := h.claimClick(r.Context(), entry.ID, shortCode, entry.MaxClicks)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to count click", "short_code", shortCode, "error", err)
+			WriteUnavailablePage(w)
+			return
+		}
+		if !allowed {
+			writeInactive(w, r, entry, stateCapped)
+			return
+		}
 	}
 
+	query := r.URL.Query()
 	err = h.clickPublisher.Publish(r.Context(), &publisher.ClicksPublisherRequest{
-		ShortCode: shortCode,
-		IPAddress: host,
-		UserAgent: r.UserAgent(),
-		Referer:   r.Referer(),
-		ClickedAt: time.Now(),
+		URLID:       entry.ID.Int64(),
+		UserID:      entry.UserID,
+		ShortCode:   shortCode,
+		IPAddress:   clientIP(r),
+		UserAgent:   r.UserAgent(),
+		Referer:     r.Referer(),
+		Variant:     target.Variant,
+		ClickedAt:   time.Now(),
+		UTMSource:   utmParam(query, "utm_source"),
+		UTMMedium:   utmParam(query, "utm_medium"),
+		UTMCampaign: utmParam(query, "utm_campaign"),
+		UTMTerm:     utmParam(query, "utm_term"),
+		UTMContent:  utmParam(query, "utm_content"),
 	})
 	if err != nil {
 		slog.ErrorContext(r.Context(), "Failed to track click", "error", err)
 	}
 
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	status := entry.RedirectStatus
+	if status == 0 {
+		status = h.defaultStatus
+	}
+
+	if target.canDeepLink() {
+		WriteDeepLinkPage(w, target.DeepLink, destination)
+		return
+	}
+
+	http.Redirect(w, r, destination, status)
 }
 
-func WriteNotFoundPage(w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "text/html")
-	fmt.Fprintf(w, `<html>
-		<head><title>404 Not Found</title></head>
-		<body>
-			<h1>404 Not Found</h1>
-			<p>The requested URL was not found on this server.</p>
-		</body>
-	</html>`)
+// resolve returns the cached redirect entry of shortCode, loading it from
+// Postgres on a miss. Entries cached before they carried the link ID and
+// owner are dropped and loaded again, since click caps and analytics need
+// them.
+func (h *RedirectHandler) resolve(ctx context.Context, shortCode string) (*cache.RedirectEntry, error) {
+	key := cache.RedirectKey(h.redirectKey, shortCode)
+	load := func() (*cache.RedirectEntry, error) {
+		val, err := h.valkey.Get(ctx, time.Minute, key, func(ctx context.Context, key string) (val string, err error) {
+			target, err := h.repo.GetRedirectTarget(ctx, domain.ShortCode(shortCode))
+			if err != nil {
+				return "", err
+			}
+
+			valkeyaside.OverrideCacheTTL(ctx, cache.RedirectTTL(&target.URL))
+
+			return cache.NewRedirectEntry(target).Encode()
+		})
+		if err != nil {
+			return nil, err
+		}
+		return cache.DecodeRedirectEntry(val)
+	}
+
+	entry, err := load()
+	if err != nil || (entry.ID != 0 && entry.UserID != "") {
+		return entry, err
+	}
+
+	if err := h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(key).Build()).Error(); err != nil {
+		return nil, err
+	}
+	return load()
+}
+
+// maxUTMLength bounds each captured UTM parameter.
+const maxUTMLength = 256
+
+// utmParam returns an incoming UTM parameter for click analytics, whether
+// or not the link forwards the query string.
+func utmParam(query url.Values, name string) string {
+	v := strings.TrimSpace(query.Get(name))
+	if len(v) > maxUTMLength {
+		v = v[:maxUTMLength]
+	}
+	return v
+}
+
+// clientIP returns the visitor address. middleware.RealIP has already
+// replaced RemoteAddr with the forwarded address when there is one.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
@@ -0,0 +1,45 @@
+package redirect
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+)
+
+var messagePage = template.Must(template.New("message").Parse(`<html>
+	<head>
+		<title>{{.Title}}</title>
+		<meta name="viewport" content="width=device-width, initial-scale=1" />
+	</head>
+	<body>
+		<h1>{{.Title}}</h1>
+		<p>{{.Message}}</p>
+	</body>
+</html>`))
+
+func writeMessagePage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	err := messagePage.Execute(w, struct{ Title, Message string }{title, message})
+	if err != nil {
+		slog.Error("Failed to write page", "status", status, "error", err)
+	}
+}
+
+func WriteNotFoundPage(w http.ResponseWriter) {
+	writeMessagePage(w, http.StatusNotFound, "404 Not Found", "The requested URL was not found on this server.")
+}
+
+// WriteGonePage is shown for links that exist but no longer redirect.
+func WriteGonePage(w http.ResponseWriter, message string) {
+	writeMessagePage(w, http.StatusGone, "Link unavailable", message)
+}
+
+// WriteUnavailablePage is shown when the link could not be looked up, so a
+// transient outage is not mistaken for a missing link.
+func WriteUnavailablePage(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "5")
+	writeMessagePage(w, http.StatusServiceUnavailable, "Temporarily unavailable", "Something went wrong on our side. Please try again in a moment.")
+}
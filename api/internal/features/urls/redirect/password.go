@@ -0,0 +1,173 @@
+package redirect
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/password"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	unlockCookiePrefix  = "refract_unlock_"
+	maxPasswordFormSize = 4 << 10
+)
+
+var passwordPage = template.Must(template.New("password").Parse(`<html>
+	<head>
+		<title>Password required</title>
+		<meta name="viewport" content="width=device-width, initial-scale=1" />
+	</head>
+	<body>
+		<h1>This link is password protected</h1>
+		{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+		<form method="post">
+			<label for="password">Password</label>
+			<input id="password" name="password" type="password" autocomplete="current-password" autofocus required />
+			<button type="submit">Continue</button>
+		</form>
+	</body>
+</html>`))
+
+func WritePasswordPage(w http.ResponseWriter, status int, errMsg string) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := passwordPage.Execute(w, struct{ Error string }{errMsg}); err != nil {
+		slog.Error("Failed to write password page", "error", err)
+	}
+}
+
+// HandleUnlock checks a password submitted from the challenge page. On success
+// it sets a signed cookie and sends the visitor back to the link with GET.
+// The link is resolved and checked like in Handle, so a password does not
+// get past a schedule, an expiry or a fallback.
+func (h *RedirectHandler) HandleUnlock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	shortCode := chi.URLParam(r, "shortCode")
+
+	attemptsKey := strings.NewReplacer("{short_code}", shortCode, "{ip}", clientIP(r)).Replace(h.attemptsKey)
+	attempts, err := h.valkey.Client().Do(ctx, h.valkey.Client().B().Get().Key(attemptsKey).Build()).AsInt64()
+	if err == nil && attempts >= int64(h.maxAttempts) {
+		WritePasswordPage(w, http.StatusTooManyRequests, "Too many incorrect attempts. Please try again later.")
+		return
+	}
+
+	entry, err := h.resolve(ctx, shortCode)
+	if errors.Is(err, domain.ErrURLNotFound) {
+		WriteNotFoundPage(w)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to resolve short code", "short_code", shortCode, "error", err)
+		WriteUnavailablePage(w)
+		return
+	}
+
+	if state := entryState(entry, time.Now()); state != stateActive {
+		writeInactive(w, r, entry, state)
+		return
+	}
+
+	if entry.PasswordVersion == "" {
+		http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+		return
+	}
+
+	// The cached entry only carries a fingerprint, the hash stays in Postgres.
+	url, err := h.repo.GetActiveURLByShortCode(ctx, domain.ShortCode(shortCode))
+	if errors.Is(err, domain.ErrURLNotFound) {
+		WriteNotFoundPage(w)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to resolve short code", "short_code", shortCode, "error", err)
+		WriteUnavailablePage(w)
+		return
+	}
+
+	if !url.PasswordProtected() {
+		http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormSize)
+	if !password.Verify(url.PasswordHash, r.PostFormValue("password")) {
+		h.recordFailedAttempt(r, attemptsKey)
+		WritePasswordPage(w, http.StatusUnauthorized, "Incorrect password.")
+		return
+	}
+
+	err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(attemptsKey).Build()).Error()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to reset password attempts", "short_code", shortCode, "error", err)
+	}
+
+	expires := time.Now().Add(h.cookieTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     unlockCookiePrefix + shortCode,
+		Value:    h.signUnlock(shortCode, password.Fingerprint(url.PasswordHash), expires),
+		Path:     "/" + shortCode,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, r.URL.RequestURI(), http.StatusSeeOther)
+}
+
+func (h *RedirectHandler) recordFailedAttempt(r *http.Request, key string) {
+	ctx := r.Context()
+	client := h.valkey.Client()
+	for _, resp := range client.DoMulti(ctx,
+		client.B().Incr().Key(key).Build(),
+		client.B().Expire().Key(key).Seconds(int64(h.attemptWindow.Seconds())).Nx().Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			slog.ErrorContext(ctx, "Failed to record password attempt", "key", key, "error", err)
+		}
+	}
+
+	slog.WarnContext(ctx, "Incorrect link password", "short_code", chi.URLParam(r, "shortCode"), "ip", clientIP(r))
+}
+
+// unlocked reports whether the request carries a valid unlock cookie for the
+// current password of the link.
+func (h *RedirectHandler) unlocked(r *http.Request, shortCode, version string) bool {
+	c, err := r.Cookie(unlockCookiePrefix + shortCode)
+	if err != nil {
+		return false
+	}
+
+	expiry, _, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+
+	expected := h.signUnlock(shortCode, version, time.Unix(unix, 0))
+	return hmac.Equal([]byte(c.Value), []byte(expected))
+}
+
+func (h *RedirectHandler) signUnlock(shortCode, version string, expires time.Time) string {
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+
+	mac := hmac.New(sha256.New, h.cookieSecret)
+	mac.Write([]byte(shortCode + "|" + version + "|" + expiry))
+
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,40 @@
+package redirect
+
+import (
+	"html/template"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var comingSoonPage = template.Must(template.New("coming-soon").Parse(`<html>
+	<head>
+		<title>Coming soon</title>
+		<meta name="viewport" content="width=device-width, initial-scale=1" />
+	</head>
+	<body>
+		<h1>Coming soon</h1>
+		<p>This link goes live on <time datetime="{{.ISO}}">{{.Display}}</time>.</p>
+	</body>
+</html>`))
+
+// WriteComingSoonPage tells visitors of a scheduled link when it launches.
+// Retry-After lets clients and crawlers come back at the right time.
+func WriteComingSoonPage(w http.ResponseWriter, activeFrom time.Time) {
+	retryAfter := math.Ceil(time.Until(activeFrom).Seconds())
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Retry-After", strconv.Itoa(int(max(retryAfter, 1))))
+	w.WriteHeader(http.StatusServiceUnavailable)
+
+	err := comingSoonPage.Execute(w, struct{ ISO, Display string }{
+		ISO:     activeFrom.UTC().Format(time.RFC3339),
+		Display: activeFrom.UTC().Format("January 2, 2006 at 15:04 UTC"),
+	})
+	if err != nil {
+		slog.Error("Failed to write coming soon page", "error", err)
+	}
+}
@@ -0,0 +1,63 @@
+package redirect
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+)
+
+// linkState is why a link that exists does or does not redirect.
+type linkState string
+
+const (
+	stateActive    linkState = "active"
+	stateDisabled  linkState = "disabled"
+	stateExpired   linkState = "expired"
+	stateCapped    linkState = "capped"
+	stateScheduled linkState = "scheduled"
+)
+
+// entryState derives the state of a cached link at now. A link that used up
+// its clicks is stored as 'expired', so an expired link with a click cap and
+// no past expiry date is reported as capped.
+func entryState(e *cache.RedirectEntry, now time.Time) linkState {
+	timeExpired := e.ExpiresAt != nil && !e.ExpiresAt.After(now)
+
+	switch {
+	case e.Status == domain.Disabled:
+		return stateDisabled
+	case timeExpired:
+		return stateExpired
+	case e.Status == domain.Expired && e.MaxClicks > 0:
+		return stateCapped
+	case e.Status == domain.Expired:
+		return stateExpired
+	case e.ActiveFrom != nil && now.Before(*e.ActiveFrom):
+		return stateScheduled
+	default:
+		return stateActive
+	}
+}
+
+// writeInactive answers a visit to a link that exists but does not redirect
+// right now. The fallback is a temporary redirect because the link may come
+// back.
+func writeInactive(w http.ResponseWriter, r *http.Request, e *cache.RedirectEntry, state linkState) {
+	if e.FallbackURL != "" {
+		http.Redirect(w, r, e.FallbackURL, http.StatusFound)
+		return
+	}
+
+	switch state {
+	case stateScheduled:
+		WriteComingSoonPage(w, *e.ActiveFrom)
+	case stateDisabled:
+		WriteGonePage(w, "This link has been disabled by its owner.")
+	case stateCapped:
+		WriteGonePage(w, "This link has reached its click limit.")
+	default:
+		WriteGonePage(w, "This link has expired.")
+	}
+}
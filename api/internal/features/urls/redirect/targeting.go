@@ -0,0 +1,87 @@
+package redirect
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/useragent"
+)
+
+// target is where a visitor is sent. DeepLink, when set, is tried before
+// URL. Variant is the A/B variant key when the destination came from a
+// split.
+type target struct {
+	URL      string
+	DeepLink string
+	Variant  string
+}
+
+// selectTarget picks the destination for this visitor. Device targets win
+// over geo targets, which win over the link destination. A/B variants split
+// the visitors that get the link destination.
+func (h *RedirectHandler) selectTarget(w http.ResponseWriter, r *http.Request, shortCode string, entry *cache.RedirectEntry) target {
+	if len(entry.DeviceTargets) > 0 {
+		if t, ok := entry.DeviceTargets[useragent.Platform(r.UserAgent())]; ok {
+			return target{URL: t.URL, DeepLink: t.DeepLink}
+		}
+	}
+
+	if len(entry.GeoTargets) > 0 && h.geo != nil {
+		if dest, ok := entry.GeoTargets[h.geo.Country(clientIP(r))]; ok {
+			return target{URL: dest}
+		}
+	}
+
+	if len(entry.Variants) > 0 {
+		v := pickVariant(w, r, shortCode, entry)
+		return target{URL: v.URL, Variant: v.Key}
+	}
+
+	return target{URL: entry.OriginalURL}
+}
+
+var deepLinkPage = template.Must(template.New("deep-link").Parse(`<html>
+	<head>
+		<title>Opening the app</title>
+		<meta name="viewport" content="width=device-width, initial-scale=1" />
+	</head>
+	<body>
+		<h1>Opening the app&hellip;</h1>
+		<p><a href="{{.DeepLink}}">Open in the app</a> or <a href="{{.Fallback}}">continue without it</a>.</p>
+		<script>
+			var fallback = {{.Fallback}};
+			var timer = setTimeout(function () { window.location.replace(fallback); }, 1500);
+			document.addEventListener("visibilitychange", function () {
+				if (document.hidden) { clearTimeout(timer); }
+			});
+			window.location.href = {{.DeepLink}};
+		</script>
+	</body>
+</html>`))
+
+// WriteDeepLinkPage tries to open the app through deepLink and sends the
+// visitor to fallback if the page is still visible shortly after, which is
+// what happens when the app is not installed.
+func WriteDeepLinkPage(w http.ResponseWriter, deepLink, fallback string) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// Custom schemes are filtered out by html/template, so the link is
+	// checked here and passed through as trusted.
+	err := deepLinkPage.Execute(w, struct {
+		DeepLink template.URL
+		Fallback string
+	}{template.URL(deepLink), fallback})
+	if err != nil {
+		slog.Error("Failed to write deep link page", "error", err)
+	}
+}
+
+// canDeepLink reports whether t should go through the deep link page.
+func (t target) canDeepLink() bool {
+	return t.DeepLink != "" && domain.IsSafeDeepLink(t.DeepLink)
+}
@@ -0,0 +1,68 @@
+package redirect
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+)
+
+const (
+	variantCookiePrefix = "refract_variant_"
+	variantCookieMaxAge = 30 * 24 * time.Hour
+)
+
+// pickVariant chooses the A/B variant for this visit in proportion to the
+// weights. Sticky links reuse the variant from the visitor's cookie while it
+// still exists and remember new picks.
+func pickVariant(w http.ResponseWriter, r *http.Request, shortCode string, entry *cache.RedirectEntry) domain.Variant {
+	name := variantCookiePrefix + shortCode
+
+	if entry.StickyVariants {
+		if c, err := r.Cookie(name); err == nil {
+			if key, err := url.QueryUnescape(c.Value); err == nil {
+				for _, v := range entry.Variants {
+					if v.Key == key {
+						return v
+					}
+				}
+			}
+		}
+	}
+
+	variant := weightedVariant(entry.Variants)
+
+	if entry.StickyVariants {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    url.QueryEscape(variant.Key),
+			Path:     "/" + shortCode,
+			MaxAge:   int(variantCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return variant
+}
+
+func weightedVariant(variants []domain.Variant) domain.Variant {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	n := rand.IntN(total)
+	for _, v := range variants {
+		if n < v.Weight {
+			return v
+		}
+		n -= v.Weight
+	}
+
+	return variants[len(variants)-1]
+}
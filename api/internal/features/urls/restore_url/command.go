@@ -0,0 +1,70 @@
+package restoreurl
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+type Command struct {
+	ID     domain.SnowflakeID
+	UserID string
+}
+
+type CommandResponse struct {
+	ShortURL string
+	Status   domain.Status
+}
+
+type CommandHandler struct {
+	repo           domain.URLRepository
+	valkey         valkeyaside.CacheAsideClient
+	chURLs         *repository.ClickHouseURLRepository
+	retention      time.Duration
+	defaultBaseURL string
+	redirectKey    string
+}
+
+func NewCommandHandler(repo domain.URLRepository, valkey valkeyaside.CacheAsideClient, chURLs *repository.ClickHouseURLRepository, retention time.Duration, defaultBaseURL, redirectKey string) *CommandHandler {
+	return &CommandHandler{
+		repo:           repo,
+		valkey:         valkey,
+		chURLs:         chURLs,
+		retention:      retention,
+		defaultBaseURL: defaultBaseURL,
+		redirectKey:    redirectKey,
+	}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*CommandResponse, error) {
+	// Links older than the retention period are waiting for the purge and
+	// can no longer be restored.
+	u, err := h.repo.Restore(ctx, cmd.ID, cmd.UserID, time.Now().Add(-h.retention))
+	if err != nil {
+		return nil, err
+	}
+
+	// Another link may have used this alias in the meantime and left its
+	// entry in the cache.
+	key := cache.RedirectKey(h.redirectKey, u.ShortCode.String())
+	err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(key).Build()).Error()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate cached short code", "short_code", u.ShortCode, "error", err)
+	}
+
+	err = h.chURLs.SaveCurrent(ctx, h.repo, u)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to restore URL in ClickHouse", "short_code", u.ShortCode, "error", err)
+	}
+
+	return &CommandResponse{
+		ShortURL: strings.Join([]string{h.defaultBaseURL, u.ShortCode.String()}, "/"),
+		Status:   u.Status,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package restoreurl
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type RestoreRequest struct {
+	ID string `path:"id"`
+}
+
+type RestoreResponse struct {
+	Body *RestoreResponseBody
+}
+
+type RestoreResponseBody struct {
+	ShortURL string        `json:"short_url"`
+	Status   domain.Status `json:"status"`
+}
+
+type Handler struct {
+	cmd *CommandHandler
+}
+
+func NewHandler(cmd *CommandHandler) *Handler {
+	return &Handler{
+		cmd: cmd,
+	}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *RestoreRequest) (*RestoreResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+
+	res, err := h.cmd.Handle(ctx, &Command{
+		ID:     domain.SnowflakeID(id),
+		UserID: userID,
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return nil, huma.Error404NotFound("URL not found in trash")
+	}
+	if errors.Is(err, domain.ErrShortCodeConflict) {
+		return nil, huma.Error409Conflict("Short code is already used by another active URL", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to restore URL", err)
+	}
+
+	return &RestoreResponse{
+		Body: &RestoreResponseBody{
+			ShortURL: res.ShortURL,
+			Status:   res.Status,
+		},
+	}, nil
+}
@@ -0,0 +1,50 @@
+package searchurls
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+type Request struct {
+	Query  string `query:"q" required:"true" minLength:"1" maxLength:"200" doc:"Fragment of a title, destination URL or short code"`
+	Cursor string `query:"cursor" doc:"Opaque cursor returned as next_cursor by the previous page"`
+	Limit  int    `query:"limit" minimum:"1" maximum:"100" default:"20"`
+}
+
+type Response struct {
+	Body *QueryResponse
+}
+
+type Handler struct {
+	query *QueryHandler
+}
+
+func NewHandler(query *QueryHandler) *Handler {
+	return &Handler{query: query}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *Request) (*Response, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	res, err := h.query.Handle(ctx, &Query{
+		userID: userID,
+		query:  strings.TrimSpace(req.Query),
+		cursor: req.Cursor,
+		limit:  req.Limit,
+	})
+	if errors.Is(err, errInvalidCursor) {
+		return nil, huma.Error400BadRequest("Invalid cursor", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to search URLs", err)
+	}
+
+	return &Response{Body: res}, nil
+}
@@ -0,0 +1,99 @@
+package searchurls
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+type Query struct {
+	userID string
+	query  string
+	cursor string
+	limit  int
+}
+
+type QueryResponse struct {
+	URLs       []URL   `json:"urls" default:"[]"`
+	NextCursor *string `json:"next_cursor" doc:"Pass as cursor to fetch the next page. Null on the last page."`
+}
+
+type URL struct {
+	ID          string        `json:"id"`
+	OriginalURL string        `json:"original_url"`
+	ShortURL    string        `json:"short_url"`
+	Title       string        `json:"title"`
+	Notes       string        `json:"notes"`
+	ExpiresAt   *time.Time    `json:"expires_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Status      domain.Status `json:"status"`
+	Rank        float32       `json:"rank" doc:"Relevance between 0 and 1, higher is better"`
+}
+
+type QueryHandler struct {
+	repo           domain.URLRepository
+	defaultBaseURL string
+}
+
+func NewQueryHandler(repo domain.URLRepository, defaultBaseURL string) *QueryHandler {
+	return &QueryHandler{
+		repo:           repo,
+		defaultBaseURL: defaultBaseURL,
+	}
+}
+
+func (h *QueryHandler) Handle(ctx context.Context, req *Query) (*QueryResponse, error) {
+	// Relevance is not a stable sort key across pages, so the cursor is an
+	// opaque offset rather than a keyset like list-urls.
+	offset := 0
+	if req.cursor != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(req.cursor)
+		if err != nil {
+			return nil, errInvalidCursor
+		}
+		offset, err = strconv.Atoi(string(raw))
+		if err != nil || offset < 0 {
+			return nil, errInvalidCursor
+		}
+	}
+
+	results, err := h.repo.Search(ctx, req.userID, req.query, req.limit+1, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextCursor *string
+	if len(results) > req.limit {
+		results = results[:req.limit]
+		c := base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset + req.limit)))
+		nextCursor = &c
+	}
+
+	converted := make([]URL, len(results))
+	for i, r := range results {
+		converted[i] = URL{
+			ID:          fmt.Sprint(r.ID.Int64()),
+			OriginalURL: r.OriginalURL,
+			ShortURL:    strings.Join([]string{h.defaultBaseURL, r.ShortCode.String()}, "/"),
+			Title:       r.Title,
+			Notes:       r.Notes,
+			ExpiresAt:   r.ExpiresAt,
+			CreatedAt:   r.CreatedAt,
+			Status:      r.Status,
+			Rank:        r.Rank,
+		}
+	}
+
+	return &QueryResponse{
+		URLs:       converted,
+		NextCursor: nextCursor,
+	}, nil
+}
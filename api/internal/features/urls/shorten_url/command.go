@@ -6,19 +6,33 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/password"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
 	"github.com/SirNacou/refract/api/internal/infrastructure/validator"
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/valkey-io/valkey-go/valkeyaside"
 )
 
 type Command struct {
-	Title       string     `validate:"required,max=255"`
-	OriginalURL string     `validate:"required,url,max=2048"`
-	UserID      string     `validate:"required"`
-	CustomAlias *string    `validate:"omitempty,max=20"`
-	ExpiresAt   *time.Time `validate:"omitempty"`
+	Title          string     `validate:"required,max=255"`
+	Notes          string     `validate:"max=1000"`
+	OriginalURL    string     `validate:"required,url,max=2048"`
+	UserID         string     `validate:"required"`
+	CustomAlias    *string    `validate:"omitempty,max=20"`
+	ExpiresAt      *time.Time `validate:"omitnil,gt"`
+	RedirectStatus int        `validate:"omitempty,oneof=301 302 307 308"`
+	ForwardQuery   bool
+	ForwardPath    bool
+	Password       string `validate:"omitempty,min=4,max=72"`
+	MaxClicks      int    `validate:"min=0"`
+	ActiveFrom     *time.Time
+	FallbackURL    string                                  `validate:"omitempty,url,max=2048"`
+	GeoTargets     map[string]string                       `validate:"max=250,dive,keys,iso3166_1_alpha2,endkeys,url,max=2048"`
+	DeviceTargets  map[domain.Platform]domain.DeviceTarget `validate:"max=3,dive,keys,oneof=ios android desktop,endkeys"`
+	Variants       []domain.Variant                        `validate:"omitempty,min=2,max=10,unique=Key,dive"`
+	StickyVariants bool
 }
 
 type CommandResponse struct {
@@ -27,23 +41,26 @@ type CommandResponse struct {
 
 type CommandHandler struct {
 	repo           domain.URLRepository
+	settings       domain.UserSettingsRepository
 	valkey         valkeyaside.CacheAsideClient
-	chConn         clickhouse.Conn
+	chURLs         *repository.ClickHouseURLRepository
 	defaultBaseURL string
 	redirectKey    string
 }
 
-func NewCommandHandler(repo domain.URLRepository, valkey valkeyaside.CacheAsideClient, chConn clickhouse.Conn, defaultBaseURL, redirectKey string) *CommandHandler {
+func NewCommandHandler(repo domain.URLRepository, settings domain.UserSettingsRepository, valkey valkeyaside.CacheAsideClient, chURLs *repository.ClickHouseURLRepository, defaultBaseURL, redirectKey string) *CommandHandler {
 	return &CommandHandler{
 		repo:           repo,
+		settings:       settings,
 		valkey:         valkey,
-		chConn:         chConn,
+		chURLs:         chURLs,
 		defaultBaseURL: defaultBaseURL,
 		redirectKey:    redirectKey,
 	}
 }
 
 func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*CommandResponse, error) {
+	cmd.GeoTargets = domain.NormalizeGeoTargets(cmd.GeoTargets)
 	err := validator.GetValidator().StructCtx(ctx, cmd)
 	if err != nil {
 		return nil, err
@@ -60,33 +77,67 @@ func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*CommandResp
 		shortCode = nil
 	}
 
-	u := domain.NewURL(cmd.OriginalURL, cmd.Title, "", cmd.UserID, shortCode, cmd.ExpiresAt)
+	u := domain.NewURL(cmd.OriginalURL, cmd.Title, cmd.Notes, cmd.UserID, shortCode, cmd.ExpiresAt)
+	u.RedirectStatus = cmd.RedirectStatus
+	u.ForwardQuery = cmd.ForwardQuery
+	u.ForwardPath = cmd.ForwardPath
+	u.MaxClicks = cmd.MaxClicks
+	u.ActiveFrom = cmd.ActiveFrom
+	u.FallbackURL = cmd.FallbackURL
+	u.GeoTargets = cmd.GeoTargets
+	u.DeviceTargets = cmd.DeviceTargets
+	u.Variants = cmd.Variants
+	u.StickyVariants = cmd.StickyVariants
+	if err := u.ValidateSchedule(); err != nil {
+		return nil, huma.Error400BadRequest("Invalid schedule", err)
+	}
+	if err := domain.ValidateDeviceTargets(u.DeviceTargets); err != nil {
+		return nil, huma.Error400BadRequest("Invalid device targets", err)
+	}
+	if cmd.Password != "" {
+		u.PasswordHash, err = password.Hash(cmd.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
 	err = h.repo.Create(ctx, u)
 	if err != nil {
 		return nil, huma.Error400BadRequest("Failed to shorten URL", err)
 	}
 
-	expiration := time.Hour * 24 * 365
-	if u.ExpiresAt != nil {
-		exp := time.Until(*u.ExpiresAt)
-		if exp < expiration {
-			expiration = exp
-		}
-	}
-
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
 
-		key := strings.Replace(h.redirectKey, "{short_code}", u.ShortCode.String(), 1)
-		err := h.valkey.Client().
+		key := cache.RedirectKey(h.redirectKey, u.ShortCode.String())
+
+		// The cached entry carries the owner's default fallback. If it cannot
+		// be loaded, drop whatever an older link with this alias left behind
+		// and let the redirector fill the cache.
+		settings, err := h.settings.Get(ctx, u.UserID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load user settings", "user_id", u.UserID, "error", err)
+			err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(key).Build()).Error()
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to invalidate cached short code", "short_code", u.ShortCode, "error", err)
+			}
+			return
+		}
+
+		entry, err := cache.NewRedirectEntry(&domain.RedirectTarget{URL: *u, UserFallbackURL: settings.FallbackURL}).Encode()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to encode redirect entry", "short_code", u.ShortCode, "error", err)
+			return
+		}
+
+		err = h.valkey.Client().
 			Do(ctx,
 				h.valkey.Client().
 					B().
 					Set().
 					Key(key).
-					Value(u.OriginalURL).
-					Ex(expiration).
+					Value(entry).
+					Ex(cache.RedirectTTL(u)).
 					Build()).
 			Error()
 		if err != nil {
@@ -97,11 +148,7 @@ func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*CommandResp
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 		defer cancel()
-		err := h.chConn.Exec(ctx, `
-	INSERT INTO refract.urls (short_code, original_url, title, created_by) VALUES (
-		?, ?, ?, ?
-	)
-	`, u.ShortCode.String(), u.OriginalURL, u.Title, u.UserID)
+		err := h.chURLs.Save(ctx, u)
 		if err != nil {
 			slog.ErrorContext(ctx, "Failed to insert URL into ClickHouse", "error", err)
 		}
@@ -2,14 +2,29 @@ package shortenurl
 
 import (
 	"context"
+	"time"
 
+	"github.com/SirNacou/refract/api/internal/domain"
 	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
 )
 
 type ShortenRequest struct {
-	Title       string `json:"title" maxLength:"255" required:"true"`
-	OriginalURL string `json:"original_url" format:"uri" required:"true"`
-	CustomAlias *string `json:"custom_alias" maxLength:"20" required:"false"`
+	Title          string                                  `json:"title" maxLength:"255" required:"true"`
+	Notes          string                                  `json:"notes" maxLength:"1000" required:"false"`
+	OriginalURL    string                                  `json:"original_url" format:"uri" required:"true"`
+	CustomAlias    *string                                 `json:"custom_alias" maxLength:"20" required:"false"`
+	ExpiresAt      *time.Time                              `json:"expires_at,omitempty" required:"false" doc:"The link stops redirecting after this time"`
+	RedirectStatus int                                     `json:"redirect_status,omitempty" enum:"301,302,307,308" required:"false" doc:"HTTP status used to redirect. Defaults to the server setting."`
+	ForwardQuery   bool                                    `json:"forward_query,omitempty" required:"false" doc:"Merge the visitor's query string into the destination. Destination parameters win on conflict."`
+	ForwardPath    bool                                    `json:"forward_path,omitempty" required:"false" doc:"Append extra path segments after the short code to the destination path"`
+	Password       string                                  `json:"password,omitempty" minLength:"4" maxLength:"72" required:"false" doc:"Visitors must enter this password before being redirected"`
+	MaxClicks      int                                     `json:"max_clicks,omitempty" minimum:"0" required:"false" doc:"Stop redirecting after this many clicks. 1 makes a one-time link."`
+	ActiveFrom     *time.Time                              `json:"active_from,omitempty" required:"false" doc:"The link shows a coming soon page until this time"`
+	FallbackURL    string                                  `json:"fallback_url,omitempty" format:"uri" maxLength:"2048" required:"false" doc:"Where visitors go while the link is expired, disabled, over its click cap or not live yet. Defaults to the account fallback."`
+	GeoTargets     map[string]string                       `json:"geo_targets,omitempty" required:"false" doc:"Destinations by visitor country, keyed by ISO 3166-1 alpha-2 code. Other countries get original_url."`
+	DeviceTargets  map[domain.Platform]domain.DeviceTarget `json:"device_targets,omitempty" required:"false" doc:"Destinations by visitor platform: ios, android or desktop. deep_link is an app URL tried before url. Takes precedence over geo_targets."`
+	Variants       []domain.Variant                        `json:"variants,omitempty" required:"false" doc:"Split visitors between 2 to 10 destinations by weight. Replaces original_url for visitors without a device or geo target."`
+	StickyVariants bool                                    `json:"sticky_variants,omitempty" required:"false" doc:"Send returning visitors to the variant they saw first, using a cookie"`
 }
 
 type ShortenResponse struct {
@@ -39,10 +54,23 @@ func (h *Handler) Handle(ctx context.Context, req *struct {
 	}
 
 	r, err := h.cmd.Handle(ctx, &Command{
-		OriginalURL: req.Body.OriginalURL,
-		UserID:      u,
-		Title:       req.Body.Title,
-		CustomAlias: req.Body.CustomAlias,
+		OriginalURL:    req.Body.OriginalURL,
+		UserID:         u,
+		Title:          req.Body.Title,
+		Notes:          req.Body.Notes,
+		CustomAlias:    req.Body.CustomAlias,
+		ExpiresAt:      req.Body.ExpiresAt,
+		RedirectStatus: req.Body.RedirectStatus,
+		ForwardQuery:   req.Body.ForwardQuery,
+		ForwardPath:    req.Body.ForwardPath,
+		Password:       req.Body.Password,
+		MaxClicks:      req.Body.MaxClicks,
+		ActiveFrom:     req.Body.ActiveFrom,
+		FallbackURL:    req.Body.FallbackURL,
+		GeoTargets:     req.Body.GeoTargets,
+		DeviceTargets:  req.Body.DeviceTargets,
+		Variants:       req.Body.Variants,
+		StickyVariants: req.Body.StickyVariants,
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,178 @@
+package updateurl
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/cache"
+	"github.com/SirNacou/refract/api/internal/infrastructure/password"
+	"github.com/SirNacou/refract/api/internal/infrastructure/repository"
+	"github.com/SirNacou/refract/api/internal/infrastructure/validator"
+	"github.com/valkey-io/valkey-go/valkeyaside"
+)
+
+// Command describes a partial update. Nil fields are left untouched.
+type Command struct {
+	ID             domain.SnowflakeID `validate:"required"`
+	UserID         string             `validate:"required"`
+	OriginalURL    *string            `validate:"omitnil,url,max=2048"`
+	Title          *string            `validate:"omitnil,min=1,max=255"`
+	Notes          *string            `validate:"omitnil,max=1000"`
+	ExpiresAt      *time.Time         `validate:"omitnil,gt"`
+	ClearExpiresAt bool
+	Status         *domain.Status `validate:"omitnil,oneof=active disabled expired"`
+	RedirectStatus *int           `validate:"omitnil,oneof=0 301 302 307 308"`
+	ForwardQuery   *bool
+	ForwardPath    *bool
+	// Password sets a new link password. An empty string removes it.
+	Password *string `validate:"omitnil,eq=|min=4,max=72"`
+	// MaxClicks sets a new click cap. 0 removes it.
+	MaxClicks *int `validate:"omitnil,min=0"`
+
+	ActiveFrom      *time.Time
+	ClearActiveFrom bool
+	// FallbackURL sets the link fallback. An empty string falls back to the
+	// owner's default again.
+	FallbackURL *string `validate:"omitnil,eq=|url,max=2048"`
+	// GeoTargets replaces the country overrides when not nil. An empty map
+	// removes them.
+	GeoTargets map[string]string `validate:"max=250,dive,keys,iso3166_1_alpha2,endkeys,url,max=2048"`
+	// DeviceTargets replaces the device targets when not nil. An empty map
+	// removes them.
+	DeviceTargets map[domain.Platform]domain.DeviceTarget `validate:"max=3,dive,keys,oneof=ios android desktop,endkeys"`
+	// Variants replaces the A/B split when not nil. An empty slice removes
+	// it.
+	Variants       []domain.Variant `validate:"omitnil,len=0|min=2,max=10,unique=Key,dive"`
+	StickyVariants *bool
+}
+
+type CommandResponse struct {
+	URL      *domain.URL
+	ShortURL string
+}
+
+type CommandHandler struct {
+	repo           domain.URLRepository
+	valkey         valkeyaside.CacheAsideClient
+	chURLs         *repository.ClickHouseURLRepository
+	defaultBaseURL string
+	redirectKey    string
+}
+
+func NewCommandHandler(repo domain.URLRepository, valkey valkeyaside.CacheAsideClient, chURLs *repository.ClickHouseURLRepository, defaultBaseURL, redirectKey string) *CommandHandler {
+	return &CommandHandler{
+		repo:           repo,
+		valkey:         valkey,
+		chURLs:         chURLs,
+		defaultBaseURL: defaultBaseURL,
+		redirectKey:    redirectKey,
+	}
+}
+
+func (h *CommandHandler) Handle(ctx context.Context, cmd *Command) (*CommandResponse, error) {
+	cmd.GeoTargets = domain.NormalizeGeoTargets(cmd.GeoTargets)
+	err := validator.GetValidator().StructCtx(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := h.repo.GetByID(ctx, cmd.ID, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.OriginalURL != nil {
+		u.OriginalURL = *cmd.OriginalURL
+	}
+	if cmd.Title != nil {
+		u.Title = *cmd.Title
+	}
+	if cmd.Notes != nil {
+		u.Notes = *cmd.Notes
+	}
+	if cmd.ClearExpiresAt {
+		u.ExpiresAt = nil
+	} else if cmd.ExpiresAt != nil {
+		u.ExpiresAt = cmd.ExpiresAt
+	}
+	if cmd.Status != nil {
+		u.Status = *cmd.Status
+	} else if u.Status == domain.Expired && (cmd.ClearExpiresAt || cmd.ExpiresAt != nil) && !u.IsExpired(time.Now()) {
+		// Extending or removing the expiry of an expired link brings it back.
+		u.Status = domain.Active
+	}
+	if cmd.RedirectStatus != nil {
+		u.RedirectStatus = *cmd.RedirectStatus
+	}
+	if cmd.ForwardQuery != nil {
+		u.ForwardQuery = *cmd.ForwardQuery
+	}
+	if cmd.ForwardPath != nil {
+		u.ForwardPath = *cmd.ForwardPath
+	}
+	if cmd.ClearActiveFrom {
+		u.ActiveFrom = nil
+	} else if cmd.ActiveFrom != nil {
+		u.ActiveFrom = cmd.ActiveFrom
+	}
+	if err := u.ValidateSchedule(); err != nil {
+		return nil, err
+	}
+	if cmd.GeoTargets != nil {
+		u.GeoTargets = cmd.GeoTargets
+	}
+	if cmd.DeviceTargets != nil {
+		if err := domain.ValidateDeviceTargets(cmd.DeviceTargets); err != nil {
+			return nil, err
+		}
+		u.DeviceTargets = cmd.DeviceTargets
+	}
+	if cmd.Variants != nil {
+		u.Variants = cmd.Variants
+	}
+	if cmd.StickyVariants != nil {
+		u.StickyVariants = *cmd.StickyVariants
+	}
+	if cmd.FallbackURL != nil {
+		u.FallbackURL = *cmd.FallbackURL
+	}
+	if cmd.MaxClicks != nil {
+		u.MaxClicks = *cmd.MaxClicks
+	}
+	if cmd.Password != nil {
+		u.PasswordHash = ""
+		if *cmd.Password != "" {
+			u.PasswordHash, err = password.Hash(*cmd.Password)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = h.repo.Update(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop the cached redirect so the redirector falls back to Postgres and
+	// stops serving the previous destination. The edit is already saved, so
+	// a failed delete is only logged and the entry runs out with its TTL.
+	key := cache.RedirectKey(h.redirectKey, u.ShortCode.String())
+	err = h.valkey.Client().Do(ctx, h.valkey.Client().B().Del().Key(key).Build()).Error()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to invalidate cached short code", "short_code", u.ShortCode, "error", err)
+	}
+
+	err = h.chURLs.SaveCurrent(ctx, h.repo, u)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to update URL in ClickHouse", "short_code", u.ShortCode, "error", err)
+	}
+
+	return &CommandResponse{
+		URL:      u,
+		ShortURL: strings.Join([]string{h.defaultBaseURL, u.ShortCode.String()}, "/"),
+	}, nil
+}
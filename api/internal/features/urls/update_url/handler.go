@@ -0,0 +1,154 @@
+package updateurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/go-playground/validator/v10"
+)
+
+type UpdateRequest struct {
+	ID   string             `path:"id"`
+	Body *UpdateRequestBody `required:"true"`
+}
+
+type UpdateRequestBody struct {
+	OriginalURL     *string                                 `json:"original_url,omitempty" format:"uri" maxLength:"2048" required:"false"`
+	Title           *string                                 `json:"title,omitempty" minLength:"1" maxLength:"255" required:"false"`
+	Notes           *string                                 `json:"notes,omitempty" maxLength:"1000" required:"false"`
+	ExpiresAt       *time.Time                              `json:"expires_at,omitempty" required:"false"`
+	ClearExpiresAt  bool                                    `json:"clear_expires_at,omitempty" required:"false" doc:"Remove the expiry date. Takes precedence over expires_at."`
+	Status          *string                                 `json:"status,omitempty" enum:"active,disabled,expired" required:"false"`
+	RedirectStatus  *int                                    `json:"redirect_status,omitempty" enum:"0,301,302,307,308" required:"false" doc:"HTTP status used to redirect. 0 resets to the server default."`
+	ForwardQuery    *bool                                   `json:"forward_query,omitempty" required:"false"`
+	ForwardPath     *bool                                   `json:"forward_path,omitempty" required:"false"`
+	Password        *string                                 `json:"password,omitempty" maxLength:"72" required:"false" doc:"New link password. An empty string removes the password."`
+	MaxClicks       *int                                    `json:"max_clicks,omitempty" minimum:"0" required:"false" doc:"New click cap, counting clicks already made. 0 removes the cap."`
+	ActiveFrom      *time.Time                              `json:"active_from,omitempty" required:"false"`
+	ClearActiveFrom bool                                    `json:"clear_active_from,omitempty" required:"false" doc:"Make the link live immediately. Takes precedence over active_from."`
+	FallbackURL     *string                                 `json:"fallback_url,omitempty" maxLength:"2048" required:"false" doc:"An empty string uses the account fallback again."`
+	GeoTargets      map[string]string                       `json:"geo_targets,omitempty" required:"false" doc:"Replaces all country overrides. An empty object removes them."`
+	DeviceTargets   map[domain.Platform]domain.DeviceTarget `json:"device_targets,omitempty" required:"false" doc:"Replaces all device targets. An empty object removes them."`
+	Variants        []domain.Variant                        `json:"variants,omitempty" required:"false" doc:"Replaces the A/B split. An empty array removes it."`
+	StickyVariants  *bool                                   `json:"sticky_variants,omitempty" required:"false"`
+}
+
+type UpdateResponse struct {
+	Body *UpdateResponseBody
+}
+
+type UpdateResponseBody struct {
+	ID                string                                  `json:"id"`
+	OriginalURL       string                                  `json:"original_url"`
+	ShortURL          string                                  `json:"short_url"`
+	Title             string                                  `json:"title"`
+	Notes             string                                  `json:"notes"`
+	ExpiresAt         *time.Time                              `json:"expires_at"`
+	UpdatedAt         time.Time                               `json:"updated_at"`
+	Status            domain.Status                           `json:"status"`
+	RedirectStatus    int                                     `json:"redirect_status" doc:"0 means the server default"`
+	ForwardQuery      bool                                    `json:"forward_query"`
+	ForwardPath       bool                                    `json:"forward_path"`
+	PasswordProtected bool                                    `json:"password_protected"`
+	MaxClicks         int                                     `json:"max_clicks" doc:"0 means no click cap"`
+	ActiveFrom        *time.Time                              `json:"active_from"`
+	FallbackURL       string                                  `json:"fallback_url"`
+	GeoTargets        map[string]string                       `json:"geo_targets"`
+	DeviceTargets     map[domain.Platform]domain.DeviceTarget `json:"device_targets"`
+	Variants          []domain.Variant                        `json:"variants"`
+	StickyVariants    bool                                    `json:"sticky_variants"`
+}
+
+type Handler struct {
+	cmd *CommandHandler
+}
+
+func NewHandler(cmd *CommandHandler) *Handler {
+	return &Handler{
+		cmd: cmd,
+	}
+}
+
+func (h *Handler) Handle(ctx context.Context, req *UpdateRequest) (*UpdateResponse, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("Unauthorized", err)
+	}
+
+	id, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+
+	res, err := h.cmd.Handle(ctx, &Command{
+		ID:              domain.SnowflakeID(id),
+		UserID:          userID,
+		OriginalURL:     req.Body.OriginalURL,
+		Title:           req.Body.Title,
+		Notes:           req.Body.Notes,
+		ExpiresAt:       req.Body.ExpiresAt,
+		ClearExpiresAt:  req.Body.ClearExpiresAt,
+		Status:          req.Body.Status,
+		RedirectStatus:  req.Body.RedirectStatus,
+		ForwardQuery:    req.Body.ForwardQuery,
+		ForwardPath:     req.Body.ForwardPath,
+		Password:        req.Body.Password,
+		MaxClicks:       req.Body.MaxClicks,
+		ActiveFrom:      req.Body.ActiveFrom,
+		ClearActiveFrom: req.Body.ClearActiveFrom,
+		FallbackURL:     req.Body.FallbackURL,
+		GeoTargets:      req.Body.GeoTargets,
+		DeviceTargets:   req.Body.DeviceTargets,
+		Variants:        req.Body.Variants,
+		StickyVariants:  req.Body.StickyVariants,
+	})
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return nil, huma.Error404NotFound("URL not found")
+	}
+	if errors.Is(err, domain.ErrShortCodeConflict) {
+		return nil, huma.Error409Conflict("Short code is already used by another active URL", err)
+	}
+	if errors.Is(err, domain.ErrInvalidSchedule) {
+		return nil, huma.Error400BadRequest("Invalid schedule", err)
+	}
+	if errors.Is(err, domain.ErrUnsafeDeepLink) {
+		return nil, huma.Error400BadRequest("Invalid device targets", err)
+	}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return nil, huma.Error400BadRequest("Invalid update", err)
+	}
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Failed to update URL", err)
+	}
+
+	return &UpdateResponse{
+		Body: &UpdateResponseBody{
+			ID:                fmt.Sprint(res.URL.ID.Int64()),
+			OriginalURL:       res.URL.OriginalURL,
+			ShortURL:          res.ShortURL,
+			Title:             res.URL.Title,
+			Notes:             res.URL.Notes,
+			ExpiresAt:         res.URL.ExpiresAt,
+			UpdatedAt:         res.URL.UpdatedAt,
+			Status:            res.URL.Status,
+			RedirectStatus:    res.URL.RedirectStatus,
+			ForwardQuery:      res.URL.ForwardQuery,
+			ForwardPath:       res.URL.ForwardPath,
+			PasswordProtected: res.URL.PasswordProtected(),
+			MaxClicks:         res.URL.MaxClicks,
+			ActiveFrom:        res.URL.ActiveFrom,
+			FallbackURL:       res.URL.FallbackURL,
+			GeoTargets:        res.URL.GeoTargets,
+			DeviceTargets:     res.URL.DeviceTargets,
+			Variants:          res.URL.Variants,
+			StickyVariants:    res.URL.StickyVariants,
+		},
+	}, nil
+}
@@ -0,0 +1,178 @@
+package botdetect
+
+import (
+	"bufio"
+	"context"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// Reasons a click is classified as a bot, stored in refract.clicks.bot_reason.
+const (
+	ReasonUserAgent = "user_agent"
+	ReasonIPRange   = "ip_range"
+	ReasonBurst     = "burst"
+)
+
+// previewMarkers are lower-case fragments of User-Agents of link unfurlers,
+// which fetch a link to show a preview wherever it is shared.
+var previewMarkers = []string{
+	"slackbot", "slack-imgproxy", "twitterbot", "facebookexternalhit", "facebot",
+	"linkedinbot", "discordbot", "telegrambot", "whatsapp", "skypeuripreview",
+	"pinterestbot", "redditbot", "embedly", "iframely", "mastodon", "bitlybot",
+	"applebot", "google-pagerenderer", "microsoftpreview",
+}
+
+// userAgentMarkers are lower-case fragments of User-Agents that belong to
+// uptime monitors, scanners and HTTP libraries rather than people.
+var userAgentMarkers = []string{
+	// Uptime monitors
+	"uptimerobot", "pingdom", "statuscake", "site24x7", "betteruptime",
+	"uptime-kuma", "newrelicpinger", "datadog", "checkly",
+	// Scanners
+	"nmap", "masscan", "zgrab", "nuclei", "sqlmap", "nikto", "censys",
+	"shodan", "expanse", "internetmeasurement",
+	// Tools and libraries
+	"curl/", "wget/", "python-requests", "python-urllib", "aiohttp",
+	"go-http-client", "okhttp", "axios", "node-fetch", "java/", "libwww-perl",
+	"headlesschrome", "phantomjs", "spider", "crawler", "bot/", "bot;",
+}
+
+// IsBotUserAgent reports whether ua is missing or matches a known automated
+// client.
+func IsBotUserAgent(ua string) bool {
+	ua = strings.ToLower(strings.TrimSpace(ua))
+	if ua == "" {
+		return true
+	}
+
+	return containsAny(ua, previewMarkers) || containsAny(ua, userAgentMarkers)
+}
+
+// IsPreviewUserAgent reports whether ua belongs to a link unfurler.
+func IsPreviewUserAgent(ua string) bool {
+	return containsAny(strings.ToLower(ua), previewMarkers)
+}
+
+func containsAny(ua string, markers []string) bool {
+	for _, m := range markers {
+		if strings.Contains(ua, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRanges reads CIDR ranges of known crawlers, one per line. Blank lines
+// and lines starting with # are skipped.
+func LoadRanges(path string) ([]netip.Prefix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ranges []netip.Prefix
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(line)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, prefix.Masked())
+	}
+
+	return ranges, scanner.Err()
+}
+
+// Ranges matches addresses against known crawler networks.
+type Ranges []netip.Prefix
+
+// Contains reports whether ip is inside one of the ranges.
+func (r Ranges) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, p := range r {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// BurstDetector flags addresses that click more than limit times within a
+// window, across all links and worker replicas.
+type BurstDetector struct {
+	client  valkey.Client
+	pattern string
+	limit   int
+	window  time.Duration
+}
+
+func NewBurstDetector(client valkey.Client, keyPattern string, limit int, window time.Duration) *BurstDetector {
+	return &BurstDetector{client: client, pattern: keyPattern, limit: limit, window: window}
+}
+
+// OverLimit records the clicks from ip with the given stream IDs in the
+// current window and returns the IDs past the limit. Clicks are ranked by
+// stream ID and recorded once, so a click delivered again keeps its place
+// instead of counting twice. Only the first limit IDs are kept, later ones
+// rank past them whenever they come back.
+func (d *BurstDetector) OverLimit(ctx context.Context, ip string, ids []string) (map[string]bool, error) {
+	key := strings.Replace(d.pattern, "{ip}", ip, 1)
+
+	add := d.client.B().Zadd().Key(key).Nx().ScoreMember()
+	for _, id := range ids {
+		add = add.ScoreMember(streamTime(id), id)
+	}
+
+	resps := d.client.DoMulti(ctx,
+		add.Build(),
+		d.client.B().Expire().Key(key).Seconds(int64(d.window.Seconds())).Nx().Build(),
+		d.client.B().Zremrangebyrank().Key(key).Start(int64(d.limit)).Stop(-1).Build(),
+		d.client.B().Zrange().Key(key).Min("0").Max("-1").Build(),
+	)
+	for _, r := range resps[:3] {
+		if err := r.Error(); err != nil {
+			return nil, err
+		}
+	}
+	within, err := resps[3].AsStrSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]bool, len(within))
+	for _, id := range within {
+		kept[id] = true
+	}
+	over := make(map[string]bool)
+	for _, id := range ids {
+		if !kept[id] {
+			over[id] = true
+		}
+	}
+	return over, nil
+}
+
+// streamTime is the millisecond part of a stream ID. IDs of the same
+// millisecond tie and are ordered by member.
+func streamTime(id string) float64 {
+	ms, _, _ := strings.Cut(id, "-")
+	t, _ := strconv.ParseFloat(ms, 64)
+	return t
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/valkey-io/valkey-go"
+)
+
+// ClickCountKey expands the configured click counter key pattern for a link.
+func ClickCountKey(pattern string, id domain.SnowflakeID) string {
+	return strings.Replace(pattern, "{id}", strconv.FormatInt(id.Int64(), 10), 1)
+}
+
+// ClickCounts returns the counted redirects per link. Links that were never
+// redirected are missing from the result.
+func ClickCounts(ctx context.Context, client valkey.Client, pattern string, ids []domain.SnowflakeID) (map[domain.SnowflakeID]int64, error) {
+	counts := make(map[domain.SnowflakeID]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = ClickCountKey(pattern, id)
+	}
+
+	values, err := client.Do(ctx, client.B().Mget().Key(keys...).Build()).ToArray()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range values {
+		n, err := v.AsInt64()
+		if err != nil {
+			continue
+		}
+		counts[ids[i]] = n
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/password"
+)
+
+// maxRedirectTTL bounds how long a redirect stays cached.
+const maxRedirectTTL = time.Hour * 24 * 365
+
+// RedirectEntry is the value cached under the redirect key. It carries
+// everything the redirector needs so a cache hit never touches Postgres.
+// Inactive links are cached too so their fallback is served from Valkey.
+type RedirectEntry struct {
+	// ID is the link the short code resolved to. Entries cached before it
+	// was added have none.
+	ID             domain.SnowflakeID `json:"id,omitempty"`
+	OriginalURL    string             `json:"original_url"`
+	Status         domain.Status      `json:"status,omitempty"`
+	ExpiresAt      *time.Time         `json:"expires_at,omitempty"`
+	FallbackURL    string             `json:"fallback_url,omitempty"`
+	RedirectStatus int                `json:"redirect_status,omitempty"`
+	ForwardQuery   bool               `json:"forward_query,omitempty"`
+	ForwardPath    bool               `json:"forward_path,omitempty"`
+
+	// UserID owns the link, clicks are recorded with it. Entries cached
+	// before it was added have none.
+	UserID string `json:"user_id,omitempty"`
+
+	// PasswordVersion is set for password-protected links. The hash itself
+	// never leaves Postgres.
+	PasswordVersion string `json:"password_version,omitempty"`
+
+	MaxClicks int `json:"max_clicks,omitempty"`
+
+	// ActiveFrom travels with the entry so a cached link is checked against
+	// its launch time on every hit instead of relying on the TTL.
+	ActiveFrom *time.Time `json:"active_from,omitempty"`
+
+	GeoTargets    map[string]string                       `json:"geo_targets,omitempty"`
+	DeviceTargets map[domain.Platform]domain.DeviceTarget `json:"device_targets,omitempty"`
+
+	Variants       []domain.Variant `json:"variants,omitempty"`
+	StickyVariants bool             `json:"sticky_variants,omitempty"`
+}
+
+func NewRedirectEntry(t *domain.RedirectTarget) *RedirectEntry {
+	u := &t.URL
+	e := &RedirectEntry{
+		ID:             u.ID,
+		UserID:         u.UserID,
+		OriginalURL:    u.OriginalURL,
+		Status:         u.Status,
+		ExpiresAt:      u.ExpiresAt,
+		FallbackURL:    t.ResolvedFallbackURL(),
+		RedirectStatus: u.RedirectStatus,
+		ForwardQuery:   u.ForwardQuery,
+		ForwardPath:    u.ForwardPath,
+		MaxClicks:      u.MaxClicks,
+		ActiveFrom:     u.ActiveFrom,
+		GeoTargets:     u.GeoTargets,
+		DeviceTargets:  u.DeviceTargets,
+		Variants:       u.Variants,
+		StickyVariants: u.StickyVariants,
+	}
+	if u.PasswordProtected() {
+		e.PasswordVersion = password.Fingerprint(u.PasswordHash)
+	}
+	return e
+}
+
+func (e *RedirectEntry) Encode() (string, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecodeRedirectEntry parses a cached value. Entries written before the
+// value became structured hold the bare destination URL.
+func DecodeRedirectEntry(s string) (*RedirectEntry, error) {
+	if !strings.HasPrefix(s, "{") {
+		return &RedirectEntry{OriginalURL: s}, nil
+	}
+
+	e := &RedirectEntry{}
+	if err := json.Unmarshal([]byte(s), e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// RedirectKey expands the configured redirect key pattern for a short code.
+func RedirectKey(pattern, shortCode string) string {
+	return strings.Replace(pattern, "{short_code}", shortCode, 1)
+}
+
+// RedirectTTL is how long the redirect for u may stay cached. It never
+// outlives the URL's expiry. Entries of already expired URLs describe that
+// state and keep the full TTL.
+func RedirectTTL(u *domain.URL) time.Duration {
+	ttl := maxRedirectTTL
+	if u.ExpiresAt != nil {
+		if untilExpiry := time.Until(*u.ExpiresAt); untilExpiry > 0 && untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+	return ttl
+}
@@ -0,0 +1,86 @@
+// Package clickhousetest runs tests against a ClickHouse server. Tests are
+// skipped unless CLICKHOUSE_TEST_ADDR is set.
+package clickhousetest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Open creates a database of its own on the server at CLICKHOUSE_TEST_ADDR,
+// runs the migrations in it and drops it when the test ends. The returned
+// connection uses that database.
+func Open(t testing.TB) driver.Conn {
+	t.Helper()
+
+	addr := os.Getenv("CLICKHOUSE_TEST_ADDR")
+	if addr == "" {
+		t.Skip("CLICKHOUSE_TEST_ADDR is not set")
+	}
+	open := func(database string) driver.Conn {
+		conn, err := clickhouse.Open(&clickhouse.Options{
+			Addr: []string{addr},
+			Auth: clickhouse.Auth{
+				Database: database,
+				Username: os.Getenv("CLICKHOUSE_TEST_USER"),
+				Password: os.Getenv("CLICKHOUSE_TEST_PASSWORD"),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	ctx := context.Background()
+	database := fmt.Sprintf("refract_test_%d", time.Now().UnixNano())
+	admin := open("")
+	if err := admin.Exec(ctx, "CREATE DATABASE "+database); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec(context.Background(), "DROP DATABASE "+database+" SYNC"); err != nil {
+			t.Errorf("drop %s: %v", database, err)
+		}
+	})
+	conn := open(database)
+
+	// The migrations name the refract database, run them against ours.
+	rename := strings.NewReplacer("refract.", database+".", "'refract'", "'"+database+"'")
+
+	_, file, _, _ := runtime.Caller(0)
+	files, err := filepath.Glob(filepath.Join(filepath.Dir(file), "../../../../clickhouse/schema/*.up.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no ClickHouse migrations found")
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, stmt := range strings.Split(string(b), ";\n") {
+			if strings.TrimSpace(stmt) == "" {
+				continue
+			}
+			if err := conn.Exec(ctx, rename.Replace(stmt)); err != nil {
+				t.Fatalf("%s: %v", filepath.Base(f), err)
+			}
+		}
+	}
+	return conn
+}
@@ -0,0 +1,199 @@
+package clickscope
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/SirNacou/refract/api/internal/infrastructure/clickhouse/clickhousetest"
+)
+
+// recentHandover is noon three days ago, so test clicks are within the TTL
+// of the clicks table.
+func recentHandover() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour).Add(-72*time.Hour + 12*time.Hour)
+}
+
+// insertClick records a click of userID. Legacy clicks have no urlID and
+// carry the owner the migration gave them.
+func insertClick(t *testing.T, conn driver.Conn, urlID domain.SnowflakeID, userID, shortCode, source string, clickedAt time.Time) {
+	t.Helper()
+
+	err := conn.Exec(context.Background(), "INSERT INTO clicks (url_id, user_id, short_code, utm_source, clicked_at, ip_address) VALUES (?, ?, ?, ?, ?, '::1')",
+		uint64(urlID), userID, shortCode, source, clickedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScopedTablesKeepTenantsApart(t *testing.T) {
+	conn := clickhousetest.Open(t)
+	ctx := context.Background()
+	handover := recentHandover()
+	linkA, linkB, otherB := reusedAlias(handover)
+	deletedB := domain.URLLifetime{URL: domain.URL{ID: 2004, ShortCode: "gone", UserID: "user-b"}}
+
+	// The day after the handover, so legacy clicks on it count for B.
+	dayAfter := handover.Add(24 * time.Hour)
+	insertClick(t, conn, linkA.ID, "user-a", "promo", "", handover.Add(-2*time.Hour))
+	insertClick(t, conn, 0, "user-a", "promo", "", handover.Add(-48*time.Hour))
+	insertClick(t, conn, linkB.ID, "user-b", "promo", "", handover.Add(time.Hour))
+	insertClick(t, conn, 0, "user-b", "promo", "", dayAfter)
+	insertClick(t, conn, otherB.ID, "user-b", "sale", "", dayAfter)
+	insertClick(t, conn, deletedB.ID, "user-b", "gone", "", dayAfter)
+
+	err := conn.Exec(ctx, "INSERT INTO links (url_id, created_by, short_code, is_deleted) VALUES (?, ?, ?, true)",
+		uint64(deletedB.ID), deletedB.UserID, deletedB.ShortCode.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Counts are by url_id, legacy clicks are under 0.
+	tests := []struct {
+		name  string
+		scope Scope
+		raw   map[domain.SnowflakeID]uint64
+		daily map[domain.SnowflakeID]uint64
+	}{
+		{
+			name:  "user A",
+			scope: Owner("user-a"),
+			raw:   map[domain.SnowflakeID]uint64{linkA.ID: 1, 0: 1},
+			daily: map[domain.SnowflakeID]uint64{linkA.ID: 1, 0: 1},
+		},
+		{
+			name:  "user B",
+			scope: Owner("user-b"),
+			raw:   map[domain.SnowflakeID]uint64{linkB.ID: 1, otherB.ID: 1, 0: 1},
+			daily: map[domain.SnowflakeID]uint64{linkB.ID: 1, otherB.ID: 1, 0: 1},
+		},
+		{
+			name:  "link of user A",
+			scope: Link(&linkA),
+			raw:   map[domain.SnowflakeID]uint64{linkA.ID: 1, 0: 1},
+			daily: map[domain.SnowflakeID]uint64{linkA.ID: 1, 0: 1},
+		},
+		{
+			name:  "link of user B on the reused alias",
+			scope: Link(&linkB),
+			raw:   map[domain.SnowflakeID]uint64{linkB.ID: 1, 0: 1},
+			daily: map[domain.SnowflakeID]uint64{linkB.ID: 1, 0: 1},
+		},
+		{
+			name:  "user without links",
+			scope: Owner("user-c"),
+			raw:   map[domain.SnowflakeID]uint64{},
+			daily: map[domain.SnowflakeID]uint64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chCtx := tt.scope.Context(ctx)
+
+			raw := countByLink(t, conn, chCtx, "SELECT url_id, count() FROM "+Clicks+" GROUP BY url_id")
+			assertCounts(t, "clicks", raw, tt.raw)
+
+			daily := countByLink(t, conn, chCtx, "SELECT url_id, sumMerge(clicks) FROM "+DailyStats+" GROUP BY url_id")
+			assertCounts(t, "daily stats", daily, tt.daily)
+		})
+	}
+}
+
+func TestDailyStatsSkipsLegacyHandoverDay(t *testing.T) {
+	conn := clickhousetest.Open(t)
+	ctx := context.Background()
+	handover := recentHandover()
+	linkA, linkB, _ := reusedAlias(handover)
+
+	// A legacy click on the day B took the alias over cannot be told apart
+	// from A's clicks that day, so the rollup gives it to neither link.
+	insertClick(t, conn, 0, "user-b", "promo", "", handover.Add(time.Hour))
+
+	for _, l := range []*domain.URLLifetime{&linkA, &linkB} {
+		daily := countByLink(t, conn, Link(l).Context(ctx), "SELECT url_id, sumMerge(clicks) FROM "+DailyStats+" GROUP BY url_id")
+		assertCounts(t, "daily stats", daily, map[domain.SnowflakeID]uint64{})
+	}
+
+	raw := countByLink(t, conn, Link(&linkB).Context(ctx), "SELECT url_id, count() FROM "+Clicks+" GROUP BY url_id")
+	assertCounts(t, "clicks", raw, map[domain.SnowflakeID]uint64{0: 1})
+}
+
+func TestBreakdownKeepsTenantsApart(t *testing.T) {
+	conn := clickhousetest.Open(t)
+	ctx := context.Background()
+	handover := recentHandover()
+	linkA, linkB, otherB := reusedAlias(handover)
+
+	insertClick(t, conn, linkA.ID, "user-a", "promo", "newsletter", handover.Add(-2*time.Hour))
+	insertClick(t, conn, linkB.ID, "user-b", "promo", "twitter", handover.Add(time.Hour))
+	insertClick(t, conn, 0, "user-b", "promo", "twitter", handover.Add(2*time.Hour))
+	insertClick(t, conn, otherB.ID, "user-b", "sale", "newsletter", handover.Add(time.Hour))
+
+	from, to := handover.Add(-24*time.Hour), handover.Add(24*time.Hour)
+	tests := []struct {
+		name  string
+		scope Scope
+		want  []Count
+	}{
+		{"user A", Owner("user-a"), []Count{{Value: "newsletter", Clicks: 1, UniqueIPs: 1}}},
+		{"user B", Owner("user-b"), []Count{{Value: "twitter", Clicks: 2, UniqueIPs: 1}, {Value: "newsletter", Clicks: 1, UniqueIPs: 1}}},
+		{"link of user B", Link(&otherB), []Count{{Value: "newsletter", Clicks: 1, UniqueIPs: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.scope.Breakdown(ctx, conn, "utm_source", from, to, true, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("item %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func countByLink(t *testing.T, conn driver.Conn, ctx context.Context, query string) map[domain.SnowflakeID]uint64 {
+	t.Helper()
+
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	counts := make(map[domain.SnowflakeID]uint64)
+	for rows.Next() {
+		var id, n uint64
+		if err := rows.Scan(&id, &n); err != nil {
+			t.Fatal(err)
+		}
+		counts[domain.SnowflakeID(id)] = n
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return counts
+}
+
+func assertCounts(t *testing.T, table string, got, want map[domain.SnowflakeID]uint64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s: got clicks of %d links %v, want %v", table, len(got), got, want)
+		return
+	}
+	for id, n := range want {
+		if got[id] != n {
+			t.Errorf("%s: link %d has %d clicks, want %d", table, id, got[id], n)
+		}
+	}
+}
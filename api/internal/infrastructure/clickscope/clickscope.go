@@ -0,0 +1,209 @@
+// Package clickscope limits click analytics to the links of one owner.
+//
+// Clicks carry the owner and the ID of the link they were made on, so
+// queries filter on them on the server whatever the number of links. Clicks
+// recorded before that were given the owner of their short code and have
+// no link ID, they belong to the link that held the short code at the time.
+//
+// Tables are named without their database, queries read those of the
+// database the connection was opened with.
+package clickscope
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+// Deleted links of the owner, by ID and, for rows without one, by the
+// tombstone of their short code.
+const (
+	deletedLinks = `
+		SELECT url_id FROM links FINAL
+		WHERE created_by = {scope_user_id:String} AND is_deleted`
+	deletedShortCodes = `
+		SELECT short_code FROM urls FINAL
+		WHERE created_by = {scope_user_id:String} AND is_deleted`
+)
+
+// Clicks is the clicks table narrowed to the clicks in scope. Use it in place
+// of the table and run the query with [Scope.Context].
+const Clicks = `(
+	SELECT *
+	FROM clicks
+	WHERE user_id = {scope_user_id:String}
+	AND ({scope_url_id:UInt64} = 0 OR short_code = {scope_short_code:String})
+	AND if(url_id = 0,
+		clicked_at >= {scope_valid_from:DateTime64(3, 'UTC')} AND clicked_at < {scope_valid_to:DateTime64(3, 'UTC')}
+			AND short_code NOT IN (` + deletedShortCodes + `),
+		({scope_url_id:UInt64} = 0 OR url_id = {scope_url_id:UInt64})
+			AND url_id NOT IN (` + deletedLinks + `))
+)`
+
+// DailyStats is the url_daily_stats table narrowed like [Clicks]. Rows
+// without a url_id only know the day, so the day a short code changed hands
+// counts for neither link.
+const DailyStats = `(
+	SELECT *
+	FROM url_daily_stats
+	WHERE user_id = {scope_user_id:String}
+	AND ({scope_url_id:UInt64} = 0 OR short_code = {scope_short_code:String})
+	AND if(url_id = 0,
+		date >= toDate({scope_valid_from:DateTime64(3, 'UTC')}, timezone()) + {scope_reused:UInt8}
+			AND date < toDate32({scope_valid_to:DateTime64(3, 'UTC')}, timezone())
+			AND short_code NOT IN (` + deletedShortCodes + `),
+		({scope_url_id:UInt64} = 0 OR url_id = {scope_url_id:UInt64})
+			AND url_id NOT IN (` + deletedLinks + `))
+)`
+
+// openEnd stands in for the end of links that still hold their short code.
+// It stays within the range of Date32 whatever the server time zone.
+var openEnd = time.Date(2299, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// Scope is the set of clicks a query may read: those of every link of an
+// owner, or those of one link.
+type Scope struct {
+	userID string
+	link   *domain.URLLifetime
+}
+
+// Owner scopes to the clicks of the links of userID that are not deleted.
+func Owner(userID string) Scope {
+	return Scope{userID: userID}
+}
+
+// Link scopes to the clicks of link, made while it held its short code.
+func Link(link *domain.URLLifetime) Scope {
+	return Scope{userID: link.UserID, link: link}
+}
+
+// Params returns the query parameters read by [Clicks] and [DailyStats],
+// in the text format ClickHouse expects for them.
+func (s Scope) Params() map[string]string {
+	params := map[string]string{
+		"scope_user_id":    escape(s.userID),
+		"scope_url_id":     "0",
+		"scope_short_code": "",
+		"scope_valid_from": time.Unix(0, 0).UTC().Format(timeLayout),
+		"scope_valid_to":   openEnd.Format(timeLayout),
+		"scope_reused":     "0",
+	}
+
+	if l := s.link; l != nil {
+		params["scope_url_id"] = strconv.FormatInt(l.ID.Int64(), 10)
+		params["scope_short_code"] = escape(l.ShortCode.String())
+		params["scope_valid_from"] = l.CreatedAt.UTC().Format(timeLayout)
+		params["scope_valid_to"] = validTo(l).UTC().Format(timeLayout)
+		if l.Reused {
+			params["scope_reused"] = "1"
+		}
+	}
+
+	return params
+}
+
+// Context returns ctx carrying the parameters of the scope for queries over
+// the native protocol.
+func (s Scope) Context(ctx context.Context) context.Context {
+	return clickhouse.Context(ctx, clickhouse.WithParameters(s.Params()))
+}
+
+// Count is the clicks on one value of a breakdown.
+type Count struct {
+	Value     string `json:"value" ch:"value" doc:"Empty when the click had no value for the dimension"`
+	Clicks    uint64 `json:"clicks" ch:"clicks"`
+	UniqueIPs uint64 `json:"unique_ips" ch:"unique_ips"`
+}
+
+// Breakdown groups the raw clicks in scope between from and to by the
+// refract.clicks expression expr, most clicked first. expr is put into the
+// SQL as is and must never come from the request.
+func (s Scope) Breakdown(ctx context.Context, ch driver.Conn, expr string, from, to time.Time, includeBots bool, limit int) ([]Count, error) {
+	rows, err := ch.Query(s.Context(ctx), `
+		SELECT `+expr+` AS value, count() AS clicks, uniq(ip_address) AS unique_ips
+		FROM `+Clicks+`
+		WHERE clicked_at >= ? AND clicked_at < ?
+		AND (? OR NOT is_bot)
+		GROUP BY value
+		ORDER BY clicks DESC, value ASC
+		LIMIT ?
+	`, from, to, includeBots, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanCounts(rows)
+}
+
+// DailyBreakdown is [Scope.Breakdown] over a column of the daily rollup,
+// which covers whole days and outlives the raw clicks.
+func (s Scope) DailyBreakdown(ctx context.Context, ch driver.Conn, column string, from, to time.Time, includeBots bool, limit int) ([]Count, error) {
+	clicks, uniqueIPs := "human_clicks", "human_unique_ips"
+	if includeBots {
+		clicks, uniqueIPs = "clicks", "unique_ips"
+	}
+
+	rows, err := ch.Query(s.Context(ctx), `
+		SELECT `+column+` AS value, sumMerge(`+clicks+`) AS clicks, uniqMerge(`+uniqueIPs+`) AS unique_ips
+		FROM `+DailyStats+`
+		WHERE date >= toDate(?) AND date <= toDate(?)
+		GROUP BY value
+		ORDER BY clicks DESC, value ASC
+		LIMIT ?
+	`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanCounts(rows)
+}
+
+func scanCounts(rows driver.Rows) ([]Count, error) {
+	defer rows.Close()
+
+	counts := make([]Count, 0)
+	for rows.Next() {
+		var c Count
+		if err := rows.ScanStruct(&c); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// Clamp narrows from and to to the span link held its short code, outside
+// of which the clicks on it belong to other links. The result is empty when
+// the range misses that span.
+func Clamp(link *domain.URLLifetime, from, to time.Time) (time.Time, time.Time) {
+	if from.Before(link.CreatedAt) {
+		from = link.CreatedAt
+	}
+	if link.EndedAt != nil && to.After(*link.EndedAt) {
+		to = *link.EndedAt
+	}
+	if to.Before(from) {
+		to = from
+	}
+	return from, to
+}
+
+func validTo(l *domain.URLLifetime) time.Time {
+	if l.EndedAt != nil {
+		return *l.EndedAt
+	}
+	return openEnd
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`)
+
+// escape writes s in the escaped format ClickHouse parses String parameters
+// with.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
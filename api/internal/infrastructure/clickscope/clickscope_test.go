@@ -0,0 +1,158 @@
+package clickscope
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+// reusedAlias returns the links of two users: linkA held "promo" until user
+// B reused it at handover with linkB, and otherB is another link of B.
+func reusedAlias(handover time.Time) (linkA, linkB, otherB domain.URLLifetime) {
+	linkA = domain.URLLifetime{
+		URL: domain.URL{
+			ID:        1001,
+			ShortCode: "promo",
+			UserID:    "user-a",
+			CreatedAt: handover.Add(-72 * time.Hour),
+		},
+		EndedAt: &handover,
+	}
+	linkB = domain.URLLifetime{
+		URL: domain.URL{
+			ID:        2002,
+			ShortCode: "promo",
+			UserID:    "user-b",
+			CreatedAt: handover,
+		},
+		Reused: true,
+	}
+	otherB = domain.URLLifetime{
+		URL: domain.URL{
+			ID:        2003,
+			ShortCode: "sale",
+			UserID:    "user-b",
+			CreatedAt: handover.Add(-96 * time.Hour),
+		},
+	}
+	return linkA, linkB, otherB
+}
+
+func TestParamsOwner(t *testing.T) {
+	got := Owner(`user\a`).Params()
+	want := map[string]string{
+		"scope_user_id":    `user\\a`,
+		"scope_url_id":     "0",
+		"scope_short_code": "",
+		"scope_valid_from": "1970-01-01 00:00:00.000",
+		"scope_valid_to":   "2299-01-01 00:00:00.000",
+		"scope_reused":     "0",
+	}
+	assertParams(t, got, want)
+}
+
+func TestParamsLink(t *testing.T) {
+	linkA, linkB, _ := reusedAlias(time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name string
+		link domain.URLLifetime
+		want map[string]string
+	}{
+		{"ended", linkA, map[string]string{
+			"scope_user_id":    "user-a",
+			"scope_url_id":     "1001",
+			"scope_short_code": "promo",
+			"scope_valid_from": "2025-03-07 12:00:00.000",
+			"scope_valid_to":   "2025-03-10 12:00:00.000",
+			"scope_reused":     "0",
+		}},
+		{"reused alias", linkB, map[string]string{
+			"scope_user_id":    "user-b",
+			"scope_url_id":     "2002",
+			"scope_short_code": "promo",
+			"scope_valid_from": "2025-03-10 12:00:00.000",
+			"scope_valid_to":   "2299-01-01 00:00:00.000",
+			"scope_reused":     "1",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertParams(t, Link(&tt.link).Params(), tt.want)
+		})
+	}
+}
+
+func assertParams(t *testing.T, got, want map[string]string) {
+	t.Helper()
+
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %q, want %q", name, got[name], w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d params, want %d", len(got), len(want))
+	}
+}
+
+// Every table the scoped queries read is filtered on the owner, so one
+// user never sees the rows of another whatever the link parameters.
+func TestScopedTablesFilterByOwner(t *testing.T) {
+	for name, sql := range map[string]string{"Clicks": Clicks, "DailyStats": DailyStats} {
+		tables := strings.Split(sql, "FROM ")[1:]
+		// The table itself and the deleted links and short codes.
+		if len(tables) != 3 {
+			t.Errorf("%s reads %d tables, want 3", name, len(tables))
+		}
+		for _, q := range tables {
+			table, _, _ := strings.Cut(q, "\n")
+			_, where, _ := strings.Cut(q, "WHERE")
+			where, _, _ = strings.Cut(where, "\n")
+			if !strings.Contains(where, "= {scope_user_id:String}") {
+				t.Errorf("%s: %s is not filtered on the owner: %s", name, table, where)
+			}
+		}
+	}
+}
+
+func TestClamp(t *testing.T) {
+	handover := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	link, _, _ := reusedAlias(handover)
+	created := link.CreatedAt
+
+	tests := []struct {
+		name             string
+		from, to         time.Time
+		wantFrom, wantTo time.Time
+	}{
+		{"within", created.Add(time.Hour), handover.Add(-time.Hour), created.Add(time.Hour), handover.Add(-time.Hour)},
+		{"starts before the link", created.Add(-48 * time.Hour), created.Add(time.Hour), created, created.Add(time.Hour)},
+		{"ends after the alias was reused", created, handover.Add(48 * time.Hour), created, handover},
+		{"covers the lifetime", created.Add(-time.Hour), handover.Add(time.Hour), created, handover},
+		{"after the alias was reused", handover.Add(time.Hour), handover.Add(48 * time.Hour), handover.Add(time.Hour), handover.Add(time.Hour)},
+		{"before the link", created.Add(-48 * time.Hour), created.Add(-24 * time.Hour), created, created},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := Clamp(&link, tt.from, tt.to)
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("got [%s, %s), want [%s, %s)", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestClampOpenEnded(t *testing.T) {
+	handover := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	_, link, _ := reusedAlias(handover)
+
+	to := handover.Add(365 * 24 * time.Hour)
+	if _, got := Clamp(&link, link.CreatedAt, to); !got.Equal(to) {
+		t.Errorf("to = %s, want %s", got, to)
+	}
+}
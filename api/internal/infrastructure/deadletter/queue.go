@@ -0,0 +1,182 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// maxLen caps the dead-letter stream so a flood of bad entries cannot
+// exhaust Valkey memory. The oldest letters are trimmed first.
+const maxLen = 100000
+
+// Letter is a stream entry the worker gave up on.
+type Letter struct {
+	ID string
+	// OriginalID is the entry ID in the source stream.
+	OriginalID string
+	// Fields are the field values of the original entry.
+	Fields     map[string]string
+	Reason     string
+	Deliveries int64
+	FailedAt   time.Time
+}
+
+// Queue is a Valkey stream holding dead letters of another stream, from
+// which they can be inspected, replayed or purged.
+type Queue struct {
+	client valkey.Client
+	key    string
+	source string
+}
+
+func NewQueue(client valkey.Client, key, sourceKey string) *Queue {
+	return &Queue{client: client, key: key, source: sourceKey}
+}
+
+// Add appends letter to the queue.
+func (q *Queue) Add(ctx context.Context, letter *Letter) error {
+	fields, err := json.Marshal(letter.Fields)
+	if err != nil {
+		return err
+	}
+
+	cmd := q.client.B().Xadd().
+		Key(q.key).
+		Maxlen().Almost().Threshold(strconv.Itoa(maxLen)).
+		Id("*").
+		FieldValue().
+		FieldValue("original_id", letter.OriginalID).
+		FieldValue("fields", string(fields)).
+		FieldValue("reason", letter.Reason).
+		FieldValue("deliveries", strconv.FormatInt(letter.Deliveries, 10)).
+		FieldValue("failed_at", letter.FailedAt.UTC().Format(time.RFC3339Nano)).
+		Build()
+
+	return q.client.Do(ctx, cmd).Error()
+}
+
+// Len returns the number of letters in the queue.
+func (q *Queue) Len(ctx context.Context) (int64, error) {
+	return q.client.Do(ctx, q.client.B().Xlen().Key(q.key).Build()).AsInt64()
+}
+
+// List returns up to count letters from the oldest, starting after the
+// letter with ID after. An empty after starts from the beginning.
+func (q *Queue) List(ctx context.Context, after string, count int) ([]Letter, error) {
+	return q.Range(ctx, after, "+", count)
+}
+
+// Range is List stopping at the letter with ID until, inclusive.
+func (q *Queue) Range(ctx context.Context, after, until string, count int) ([]Letter, error) {
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+
+	cmd := q.client.B().Xrange().Key(q.key).Start(start).End(until).Count(int64(count)).Build()
+	entries, err := q.client.Do(ctx, cmd).AsXRange()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]Letter, 0, len(entries))
+	for _, e := range entries {
+		letters = append(letters, toLetter(e))
+	}
+	return letters, nil
+}
+
+// LastID returns the ID of the newest letter, or "" when the queue is
+// empty.
+func (q *Queue) LastID(ctx context.Context) (string, error) {
+	cmd := q.client.B().Xrevrange().Key(q.key).End("+").Start("-").Count(1).Build()
+	entries, err := q.client.Do(ctx, cmd).AsXRange()
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	return entries[0].ID, nil
+}
+
+// Get returns the letters with the given IDs. Unknown IDs are skipped.
+func (q *Queue) Get(ctx context.Context, ids []string) ([]Letter, error) {
+	cmds := make(valkey.Commands, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, q.client.B().Xrange().Key(q.key).Start(id).End(id).Build())
+	}
+
+	letters := make([]Letter, 0, len(ids))
+	for _, res := range q.client.DoMulti(ctx, cmds...) {
+		entries, err := res.AsXRange()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			letters = append(letters, toLetter(e))
+		}
+	}
+	return letters, nil
+}
+
+// Replay adds the original entries of letters back to the source stream
+// and removes them from the queue. Letters without fields are left alone.
+// It returns how many were replayed.
+func (q *Queue) Replay(ctx context.Context, letters []Letter) (int, error) {
+	replayed := 0
+	for _, l := range letters {
+		if len(l.Fields) == 0 {
+			continue
+		}
+
+		fv := q.client.B().Xadd().Key(q.source).Id("*").FieldValue()
+		for f, v := range l.Fields {
+			fv = fv.FieldValue(f, v)
+		}
+		if err := q.client.Do(ctx, fv.Build()).Error(); err != nil {
+			return replayed, err
+		}
+
+		if _, err := q.Delete(ctx, l.ID); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, nil
+}
+
+// Delete removes the letters with the given IDs and returns how many
+// existed.
+func (q *Queue) Delete(ctx context.Context, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return q.client.Do(ctx, q.client.B().Xdel().Key(q.key).Id(ids...).Build()).AsInt64()
+}
+
+// Purge removes every letter and returns how many there were.
+func (q *Queue) Purge(ctx context.Context) (int64, error) {
+	n, err := q.Len(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := q.client.Do(ctx, q.client.B().Del().Key(q.key).Build()).Error(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func toLetter(e valkey.XRangeEntry) Letter {
+	l := Letter{
+		ID:         e.ID,
+		OriginalID: e.FieldValues["original_id"],
+		Reason:     e.FieldValues["reason"],
+	}
+	// Malformed metadata only loses detail, the letter is still listed.
+	_ = json.Unmarshal([]byte(e.FieldValues["fields"]), &l.Fields)
+	l.Deliveries, _ = strconv.ParseInt(e.FieldValues["deliveries"], 10, 64)
+	l.FailedAt, _ = time.Parse(time.RFC3339Nano, e.FieldValues["failed_at"])
+	return l
+}
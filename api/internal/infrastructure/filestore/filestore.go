@@ -0,0 +1,90 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var ErrInvalidName = errors.New("invalid file name")
+
+// Store keeps files in a local directory. Files are written to a temporary
+// name and renamed once complete, so readers never see partial files.
+type Store struct {
+	dir string
+}
+
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Write creates the file name with the output of write and returns its
+// size. Nothing is left behind when write fails.
+func (s *Store) Write(name string, write func(w io.Writer) error) (int64, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return 0, err
+	}
+	f, err := os.CreateTemp(s.dir, "."+name+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+
+	if err := write(f); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Open opens the file name for reading.
+func (s *Store) Open(name string) (*os.File, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Remove deletes the file name. Missing files are not an error.
+func (s *Store) Remove(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name[0] == '.' {
+		return "", fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
@@ -0,0 +1,174 @@
+package geoip
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// Reader resolves IP addresses against a local MaxMind-format database.
+// Lookups are memory-mapped reads and never leave the process.
+type Reader struct {
+	path string
+
+	mu      sync.RWMutex
+	db      *maxminddb.Reader
+	modTime time.Time
+}
+
+// Location is where an address is registered. Fields the database does not
+// carry are empty.
+type Location struct {
+	Country string
+	Region  string
+	City    string
+}
+
+// Network is the autonomous system announcing an address.
+type Network struct {
+	ASN          uint32
+	Organization string
+}
+
+func Open(path string) (*Reader, error) {
+	r := &Reader{path: path}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reader) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+
+	db, err := maxminddb.Open(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	old := r.db
+	r.db = db
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+
+	if old != nil {
+		return old.Close()
+	}
+	return nil
+}
+
+// Watch reopens the database whenever the file's modification time changes,
+// checking every interval until ctx is done. A file that fails to open keeps
+// the previous database in use.
+func (r *Reader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(r.path)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to stat GeoIP database", "path", r.path, "error", err)
+			continue
+		}
+
+		r.mu.RLock()
+		changed := !info.ModTime().Equal(r.modTime)
+		r.mu.RUnlock()
+		if !changed {
+			continue
+		}
+
+		if err := r.load(); err != nil {
+			slog.ErrorContext(ctx, "Failed to reload GeoIP database", "path", r.path, "error", err)
+			continue
+		}
+		slog.InfoContext(ctx, "Reloaded GeoIP database", "path", r.path)
+	}
+}
+
+func (r *Reader) lookup(ip string, v any) error {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Lookup(addr.Unmap()).Decode(v)
+}
+
+// Country returns the upper-case ISO 3166-1 alpha-2 code for ip, or an empty
+// string when the address is invalid, private or not in the database.
+func (r *Reader) Country(ip string) string {
+	var rec struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := r.lookup(ip, &rec); err != nil {
+		return ""
+	}
+	return strings.ToUpper(rec.Country.ISOCode)
+}
+
+// Location looks ip up in a City database. Region is the first subdivision
+// and names are in English.
+func (r *Reader) Location(ip string) Location {
+	var rec struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		Subdivisions []struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"subdivisions"`
+		City struct {
+			Names map[string]string `maxminddb:"names"`
+		} `maxminddb:"city"`
+	}
+	if err := r.lookup(ip, &rec); err != nil {
+		return Location{}
+	}
+
+	loc := Location{
+		Country: strings.ToUpper(rec.Country.ISOCode),
+		City:    rec.City.Names["en"],
+	}
+	if len(rec.Subdivisions) > 0 {
+		loc.Region = rec.Subdivisions[0].Names["en"]
+	}
+	return loc
+}
+
+// Network looks ip up in an ASN database.
+func (r *Reader) Network(ip string) Network {
+	var rec struct {
+		ASN          uint32 `maxminddb:"autonomous_system_number"`
+		Organization string `maxminddb:"autonomous_system_organization"`
+	}
+	if err := r.lookup(ip, &rec); err != nil {
+		return Network{}
+	}
+	return Network{ASN: rec.ASN, Organization: rec.Organization}
+}
+
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.db.Close()
+}
@@ -0,0 +1,160 @@
+package livestream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/publisher"
+	"github.com/valkey-io/valkey-go"
+)
+
+// Hub tails the clicks stream with a plain XREAD, outside the worker's
+// consumer group, and fans each click out to its subscribers. One tail runs
+// per process while anyone is subscribed.
+//
+// Delivery never blocks: a subscriber whose buffer is full misses the click
+// and is told how many it missed, so a slow client cannot hold up the tail,
+// other clients or the redirector writing to the stream.
+type Hub struct {
+	client valkey.Client
+	key    string
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	cancel context.CancelFunc
+}
+
+func NewHub(client valkey.Client, streamKey string) *Hub {
+	return &Hub{
+		client: client,
+		key:    streamKey,
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the clicks accepted by its filter.
+type Subscription struct {
+	events  chan *publisher.ClicksPublisherRequest
+	filter  func(*publisher.ClicksPublisherRequest) bool
+	dropped atomic.Int64
+}
+
+// Events delivers matching clicks in stream order.
+func (s *Subscription) Events() <-chan *publisher.ClicksPublisherRequest {
+	return s.events
+}
+
+// TakeDropped returns how many clicks were missed since the last call.
+func (s *Subscription) TakeDropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Subscribe registers a subscriber that buffers up to buffer clicks. filter
+// runs on the tail goroutine and must be cheap.
+func (h *Hub) Subscribe(buffer int, filter func(*publisher.ClicksPublisherRequest) bool) *Subscription {
+	s := &Subscription{
+		events: make(chan *publisher.ClicksPublisherRequest, buffer),
+		filter: filter,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subs[s] = struct{}{}
+	if h.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancel = cancel
+		go h.tail(ctx)
+	}
+
+	return s
+}
+
+// Unsubscribe removes s and stops the tail when it was the last subscriber.
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subs, s)
+	if len(h.subs) == 0 && h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+func (h *Hub) tail(ctx context.Context) {
+	lastID := h.lastID(ctx)
+
+	for ctx.Err() == nil {
+		cmd := h.client.B().Xread().
+			Count(500).
+			Block((time.Second * 5).Milliseconds()).
+			Streams().
+			Key(h.key).
+			Id(lastID).
+			Build()
+
+		res, err := h.client.Do(ctx, cmd).AsXRead()
+		if valkey.IsValkeyNil(err) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to tail clicks stream", "error", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		for _, entry := range res[h.key] {
+			lastID = entry.ID
+
+			data, ok := entry.FieldValues["data"]
+			if !ok {
+				continue
+			}
+
+			click := &publisher.ClicksPublisherRequest{}
+			if err := json.Unmarshal([]byte(data), click); err != nil {
+				continue
+			}
+
+			h.broadcast(click)
+		}
+	}
+}
+
+// lastID returns the ID of the newest entry so the tail starts after it. $
+// is only a fallback because it would skip clicks added between two reads.
+func (h *Hub) lastID(ctx context.Context) string {
+	entries, err := h.client.Do(ctx, h.client.B().Xrevrange().Key(h.key).End("+").Start("-").Count(1).Build()).AsXRange()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read newest click", "error", err)
+		return "$"
+	}
+	if len(entries) == 0 {
+		return "0-0"
+	}
+	return entries[0].ID
+}
+
+func (h *Hub) broadcast(click *publisher.ClicksPublisherRequest) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subs {
+		if !s.filter(click) {
+			continue
+		}
+
+		select {
+		case s.events <- click:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
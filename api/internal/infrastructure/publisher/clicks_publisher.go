@@ -21,11 +21,20 @@ func NewClicksPublisher(valkey valkey.Client, clicksStreamKey string) *ClicksPub
 }
 
 type ClicksPublisherRequest struct {
+	URLID     int64     `json:"url_id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
 	ShortCode string    `json:"short_code"`
 	IPAddress string    `json:"ip_address,omitempty"`
 	UserAgent string    `json:"user_agent,omitempty"`
 	Referer   string    `json:"referer,omitempty"`
+	Variant   string    `json:"variant,omitempty"`
 	ClickedAt time.Time `json:"clicked_at"`
+
+	UTMSource   string `json:"utm_source,omitempty"`
+	UTMMedium   string `json:"utm_medium,omitempty"`
+	UTMCampaign string `json:"utm_campaign,omitempty"`
+	UTMTerm     string `json:"utm_term,omitempty"`
+	UTMContent  string `json:"utm_content,omitempty"`
 }
 
 func (ct *ClicksPublisher) Publish(ctx context.Context, req *ClicksPublisherRequest) error {
@@ -0,0 +1,110 @@
+package referrer
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Channels a click is attributed to.
+const (
+	ChannelDirect   = "direct"
+	ChannelSearch   = "search"
+	ChannelSocial   = "social"
+	ChannelEmail    = "email"
+	ChannelReferral = "referral"
+)
+
+// Domains are matched exactly or as a parent of the referring host. Webmail
+// comes first so mail.google.com is not taken for Google search.
+var (
+	emailDomains = []string{
+		"mail.google.com", "outlook.live.com", "outlook.office.com", "outlook.office365.com",
+		"mail.yahoo.com", "mail.proton.me", "mail.aol.com", "mail.zoho.com", "fastmail.com",
+	}
+	socialDomains = []string{
+		"facebook.com", "fb.me", "instagram.com", "t.co", "twitter.com", "x.com",
+		"linkedin.com", "lnkd.in", "reddit.com", "pinterest.com", "tiktok.com",
+		"youtube.com", "youtu.be", "threads.net", "bsky.app", "mastodon.social",
+		"news.ycombinator.com", "t.me", "telegram.org", "whatsapp.com", "discord.com",
+		"slack.com", "snapchat.com", "tumblr.com", "quora.com", "vk.com",
+	}
+	searchDomains = []string{
+		"bing.com", "duckduckgo.com", "baidu.com", "ecosia.org", "search.brave.com",
+		"startpage.com", "naver.com", "seznam.cz", "qwant.com", "kagi.com",
+	}
+	// searchNames match any country domain, e.g. google.co.uk.
+	searchNames = []string{"google", "yahoo", "yandex"}
+)
+
+// Domain returns the lower-case host of referer without a leading "www.",
+// or an empty string when referer is not an absolute URL.
+func Domain(referer string) string {
+	u, err := url.Parse(strings.TrimSpace(referer))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Channel attributes a click from its referring domain. A utm_medium naming
+// a known channel takes precedence, since email clients and apps rarely send
+// a referer.
+func Channel(domain, utmMedium string) string {
+	switch strings.ToLower(strings.TrimSpace(utmMedium)) {
+	case "email", "e-mail", "newsletter":
+		return ChannelEmail
+	case "social", "social-media", "social_media", "sm":
+		return ChannelSocial
+	case "cpc", "ppc", "paid-search", "paidsearch", "organic":
+		return ChannelSearch
+	}
+
+	switch {
+	case domain == "":
+		return ChannelDirect
+	case matches(domain, emailDomains):
+		return ChannelEmail
+	case matches(domain, socialDomains):
+		return ChannelSocial
+	case matches(domain, searchDomains) || hasLabel(domain, searchNames):
+		return ChannelSearch
+	default:
+		return ChannelReferral
+	}
+}
+
+func matches(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLabel reports whether one of names is a label of host followed by what
+// looks like a public suffix, e.g. google in search.google.com.br.
+func hasLabel(host string, names []string) bool {
+	labels := strings.Split(host, ".")
+	for i, l := range labels {
+		suffix := labels[i+1:]
+		if len(suffix) == 0 || len(suffix) > 2 {
+			continue
+		}
+		for _, n := range names {
+			if l == n && shortLabels(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func shortLabels(labels []string) bool {
+	for _, l := range labels {
+		if len(l) > 3 {
+			return false
+		}
+	}
+	return true
+}
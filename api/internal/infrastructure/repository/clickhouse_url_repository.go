@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+// ClickHouseURLRepository keeps the refract.urls analytics table in sync with
+// Postgres. The table is a ReplacingMergeTree keyed on short_code, so every
+// write inserts a new version of the row instead of mutating it.
+// refract.links is its counterpart keyed on the link ID.
+type ClickHouseURLRepository struct {
+	conn driver.Conn
+}
+
+func NewClickHouseURLRepository(conn driver.Conn) *ClickHouseURLRepository {
+	return &ClickHouseURLRepository{
+		conn: conn,
+	}
+}
+
+// Save writes the latest version of the URL. Soft-deleted URLs are written
+// as tombstones so analytics queries can skip them.
+func (r *ClickHouseURLRepository) Save(ctx context.Context, url *domain.URL) error {
+	return r.conn.Exec(ctx, `
+	INSERT INTO refract.urls (short_code, original_url, title, created_by, status, is_deleted) VALUES (
+		?, ?, ?, ?, ?, ?
+	)
+	`, url.ShortCode.String(), url.OriginalURL, url.Title, url.UserID, url.Status, url.DeletedAt != nil)
+}
+
+// SaveCurrent is Save for a URL that may have given up its short code. The
+// row of a short code belongs to the link holding it now, so the URL is
+// only written while no later link has taken its short code. Deleted URLs
+// are checked too, their tombstone must reach the table. The refract.links
+// row of the URL is written either way.
+func (r *ClickHouseURLRepository) SaveCurrent(ctx context.Context, urls domain.URLRepository, url *domain.URL) error {
+	err := r.conn.Exec(ctx, `
+	INSERT INTO refract.links (url_id, created_by, short_code, is_deleted) VALUES (
+		?, ?, ?, ?
+	)
+	`, uint64(url.ID), url.UserID, url.ShortCode.String(), url.DeletedAt != nil)
+	if err != nil {
+		return err
+	}
+
+	holds, err := urls.HoldsShortCode(ctx, url)
+	if err != nil {
+		return err
+	}
+	if !holds {
+		return nil
+	}
+	return r.Save(ctx, url)
+}
+
+// SaveAll writes the latest version of several URLs in one batch.
+func (r *ClickHouseURLRepository) SaveAll(ctx context.Context, urls []domain.URL) error {
+	batch, err := r.conn.PrepareBatch(ctx, "INSERT INTO refract.urls (short_code, original_url, title, created_by, status, is_deleted)")
+	if err != nil {
+		return err
+	}
+	defer batch.Close()
+
+	for _, url := range urls {
+		err = batch.Append(url.ShortCode.String(), url.OriginalURL, url.Title, url.UserID, url.Status, url.DeletedAt != nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return batch.Send()
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/SirNacou/refract/api/internal/domain"
+)
+
+// fakeURLRepository answers HoldsShortCode like the Postgres query: every
+// link counts, deleted or not.
+type fakeURLRepository struct {
+	domain.URLRepository
+	links []domain.URL
+}
+
+func (r *fakeURLRepository) HoldsShortCode(_ context.Context, url *domain.URL) (bool, error) {
+	for _, l := range r.links {
+		if l.ShortCode == url.ShortCode && l.CreatedAt.After(url.CreatedAt) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type fakeConn struct {
+	driver.Conn
+	written map[string][][]any
+}
+
+func (c *fakeConn) Exec(_ context.Context, query string, args ...any) error {
+	for _, table := range []string{"refract.urls", "refract.links"} {
+		if strings.Contains(query, "INSERT INTO "+table+" ") {
+			c.written[table] = append(c.written[table], args)
+		}
+	}
+	return nil
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{written: make(map[string][][]any)}
+}
+
+// Deleting or restoring a link after its alias was reused must not replace
+// the row of the link holding the alias now.
+func TestSaveCurrentSkipsReusedShortCode(t *testing.T) {
+	handover := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	deleted := handover.Add(time.Hour)
+	old := domain.URL{
+		ID:        1001,
+		ShortCode: "promo",
+		UserID:    "user-a",
+		CreatedAt: handover.Add(-72 * time.Hour),
+		DeletedAt: &deleted,
+	}
+	current := domain.URL{
+		ID:        2002,
+		ShortCode: "promo",
+		UserID:    "user-b",
+		CreatedAt: handover,
+	}
+	urls := &fakeURLRepository{links: []domain.URL{old, current}}
+
+	conn := newFakeConn()
+	r := NewClickHouseURLRepository(conn)
+
+	if err := r.SaveCurrent(context.Background(), urls, &old); err != nil {
+		t.Fatal(err)
+	}
+	if rows := conn.written["refract.urls"]; len(rows) != 0 {
+		t.Fatalf("wrote %v for a link that no longer holds its short code", rows)
+	}
+	if rows := conn.written["refract.links"]; len(rows) != 1 || rows[0][0] != uint64(1001) || rows[0][3] != true {
+		t.Errorf("wrote links %v, want the deleted link", rows)
+	}
+
+	if err := r.SaveCurrent(context.Background(), urls, &current); err != nil {
+		t.Fatal(err)
+	}
+	if rows := conn.written["refract.urls"]; len(rows) != 1 || rows[0][0] != "promo" || rows[0][3] != "user-b" {
+		t.Errorf("wrote %v, want the row of the current link", rows)
+	}
+}
+
+// Deleting the link that holds its alias must write the tombstone, right
+// after the soft delete hid the link from the lifetime queries.
+func TestSaveCurrentWritesTombstone(t *testing.T) {
+	created := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	deleted := created.Add(time.Hour)
+	link := domain.URL{
+		ID:        1001,
+		ShortCode: "promo",
+		UserID:    "user-a",
+		CreatedAt: created,
+		DeletedAt: &deleted,
+	}
+	urls := &fakeURLRepository{links: []domain.URL{link}}
+
+	conn := newFakeConn()
+	r := NewClickHouseURLRepository(conn)
+
+	if err := r.SaveCurrent(context.Background(), urls, &link); err != nil {
+		t.Fatal(err)
+	}
+	rows := conn.written["refract.urls"]
+	if len(rows) != 1 {
+		t.Fatalf("wrote %d rows, want the tombstone", len(rows))
+	}
+	if row := rows[0]; row[0] != "promo" || row[5] != true {
+		t.Errorf("wrote %v, want promo with is_deleted set", row)
+	}
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SirNacou/refract/api/internal/db"
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+type PostgresExportRepository struct {
+	querier db.Querier
+}
+
+func NewPostgresExportRepository(querier db.Querier) domain.ExportRepository {
+	return &PostgresExportRepository{
+		querier: querier,
+	}
+}
+
+// Create implements [domain.ExportRepository].
+func (p *PostgresExportRepository) Create(ctx context.Context, export *domain.Export) error {
+	var urlID *int64
+	if export.URLID != nil {
+		id := export.URLID.Int64()
+		urlID = &id
+	}
+	e, err := p.querier.CreateExport(ctx, db.CreateExportParams{
+		ID:        export.ID.Int64(),
+		UserID:    export.UserID,
+		UrlID:     urlID,
+		ShortCode: toNullableString(export.ShortCode),
+		Format:    export.Format,
+		RangeFrom: export.From,
+		RangeTo:   export.To,
+	})
+	if err != nil {
+		return err
+	}
+
+	*export = *toDomainExport(&e)
+	return nil
+}
+
+// Get implements [domain.ExportRepository].
+func (p *PostgresExportRepository) Get(ctx context.Context, id domain.SnowflakeID, userID string) (*domain.Export, error) {
+	e, err := p.querier.GetExport(ctx, db.GetExportParams{
+		ID:     id.Int64(),
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainExport(&e), nil
+}
+
+// ListByUser implements [domain.ExportRepository].
+func (p *PostgresExportRepository) ListByUser(ctx context.Context, userID string, limit int) ([]domain.Export, error) {
+	exports, err := p.querier.ListExportsByUser(ctx, db.ListExportsByUserParams{
+		UserID: userID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainExports(exports), nil
+}
+
+// Claim implements [domain.ExportRepository].
+func (p *PostgresExportRepository) Claim(ctx context.Context, staleBefore time.Time) (*domain.Export, error) {
+	e, err := p.querier.ClaimExport(ctx, &staleBefore)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainExport(&e), nil
+}
+
+// Complete implements [domain.ExportRepository].
+func (p *PostgresExportRepository) Complete(ctx context.Context, export *domain.Export) error {
+	e, err := p.querier.CompleteExport(ctx, db.CompleteExportParams{
+		ID:        export.ID.Int64(),
+		FileName:  &export.FileName,
+		SizeBytes: &export.SizeBytes,
+		ExpiresAt: export.ExpiresAt,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrExportNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	*export = *toDomainExport(&e)
+	return nil
+}
+
+// Fail implements [domain.ExportRepository].
+func (p *PostgresExportRepository) Fail(ctx context.Context, export *domain.Export) error {
+	e, err := p.querier.FailExport(ctx, db.FailExportParams{
+		ID:        export.ID.Int64(),
+		Error:     toNullableString(export.Error),
+		ExpiresAt: export.ExpiresAt,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrExportNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	*export = *toDomainExport(&e)
+	return nil
+}
+
+// DeleteExpired implements [domain.ExportRepository].
+func (p *PostgresExportRepository) DeleteExpired(ctx context.Context) ([]domain.Export, error) {
+	exports, err := p.querier.DeleteExpiredExports(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainExports(exports), nil
+}
+
+func toDomainExports(exports []db.Export) []domain.Export {
+	result := make([]domain.Export, 0, len(exports))
+	for _, e := range exports {
+		result = append(result, *toDomainExport(&e))
+	}
+	return result
+}
+
+func toDomainExport(e *db.Export) *domain.Export {
+	export := &domain.Export{
+		ID:          domain.SnowflakeID(e.ID),
+		UserID:      e.UserID,
+		Format:      e.Format,
+		From:        e.RangeFrom,
+		To:          e.RangeTo,
+		Status:      e.Status,
+		CreatedAt:   e.CreatedAt,
+		StartedAt:   e.StartedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+	}
+	if e.UrlID != nil {
+		id := domain.SnowflakeID(*e.UrlID)
+		export.URLID = &id
+	}
+	if e.ShortCode != nil {
+		export.ShortCode = *e.ShortCode
+	}
+	if e.FileName != nil {
+		export.FileName = *e.FileName
+	}
+	if e.SizeBytes != nil {
+		export.SizeBytes = *e.SizeBytes
+	}
+	if e.Error != nil {
+		export.Error = *e.Error
+	}
+	return export
+}
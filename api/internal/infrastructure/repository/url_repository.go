@@ -2,12 +2,24 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/SirNacou/refract/api/internal/db"
 	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// likeEscaper escapes user input so it is matched literally inside ILIKE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// uniqueViolation is the Postgres error code raised by idx_urls_active_short_code.
+const uniqueViolation = "23505"
+
 type PostgresURLRepository struct {
 	querier db.Querier
 }
@@ -19,14 +31,37 @@ func NewPostgresURLRepository(querier db.Querier) domain.URLRepository {
 }
 
 // ListByUser implements [domain.URLRepository].
-func (p *PostgresURLRepository) ListByUser(ctx context.Context, userID string) ([]domain.URL, error) {
-	urls, err := p.querier.ListURLs(ctx, userID)
+func (p *PostgresURLRepository) ListByUser(ctx context.Context, filter domain.URLFilter) ([]domain.URL, error) {
+	params := db.ListURLsParams{
+		UserID:        filter.UserID,
+		Status:        filter.Status,
+		CreatedAfter:  filter.CreatedAfter,
+		CreatedBefore: filter.CreatedBefore,
+		ExpiresAfter:  filter.ExpiresAfter,
+		ExpiresBefore: filter.ExpiresBefore,
+		PageSize:      int32(filter.Limit),
+	}
+	if filter.After != nil {
+		cursorID := filter.After.ID.Int64()
+		params.CursorCreatedAt = &filter.After.CreatedAt
+		params.CursorID = &cursorID
+	}
+
+	var (
+		urls []db.Url
+		err  error
+	)
+	if filter.Ascending {
+		urls, err = p.querier.ListURLsAsc(ctx, db.ListURLsAscParams(params))
+	} else {
+		urls, err = p.querier.ListURLs(ctx, params)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	if len(urls) == 0 {
-		slog.Info("returning empty array of URLs for user", "userID", userID)
+		slog.Info("returning empty array of URLs for user", "userID", filter.UserID)
 	}
 
 	result := make([]domain.URL, 0, len(urls))
@@ -37,9 +72,132 @@ func (p *PostgresURLRepository) ListByUser(ctx context.Context, userID string) (
 	return result, nil
 }
 
+// Search implements [domain.URLRepository].
+func (p *PostgresURLRepository) Search(ctx context.Context, userID, query string, limit, offset int) ([]domain.URLSearchResult, error) {
+	rows, err := p.querier.SearchURLs(ctx, db.SearchURLsParams{
+		Query:      query,
+		UserID:     userID,
+		Pattern:    "%" + likeEscaper.Replace(query) + "%",
+		PageSize:   int32(limit),
+		PageOffset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.URLSearchResult, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, domain.URLSearchResult{
+			URL:  *toDomainURL(&r.Url),
+			Rank: r.Rank,
+		})
+	}
+
+	return result, nil
+}
+
 // FirstByShortCode implements [domain.URLRepository].
 func (p *PostgresURLRepository) GetActiveURLByShortCode(ctx context.Context, shortCode domain.ShortCode) (*domain.URL, error) {
 	url, err := p.querier.GetActiveURLByShortCode(ctx, shortCode.String())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainURL(&url), nil
+}
+
+// GetRedirectTarget implements [domain.URLRepository].
+func (p *PostgresURLRepository) GetRedirectTarget(ctx context.Context, shortCode domain.ShortCode) (*domain.RedirectTarget, error) {
+	row, err := p.querier.GetRedirectURLByShortCode(ctx, shortCode.String())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	target := &domain.RedirectTarget{URL: *toDomainURL(&row.Url)}
+	if row.UserFallbackUrl != nil {
+		target.UserFallbackURL = *row.UserFallbackUrl
+	}
+
+	return target, nil
+}
+
+// ListLifetimesByUser implements [domain.URLRepository].
+func (p *PostgresURLRepository) ListLifetimesByUser(ctx context.Context, userID string) ([]domain.URLLifetime, error) {
+	rows, err := p.querier.ListURLLifetimesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.URLLifetime, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, domain.URLLifetime{
+			URL:     *toDomainURL(&r.Url),
+			EndedAt: r.EndedAt,
+			Reused:  r.Reused,
+		})
+	}
+
+	return result, nil
+}
+
+// GetLifetime implements [domain.URLRepository].
+func (p *PostgresURLRepository) GetLifetime(ctx context.Context, id domain.SnowflakeID, userID string) (*domain.URLLifetime, error) {
+	row, err := p.querier.GetURLLifetime(ctx, db.GetURLLifetimeParams{
+		ID:     id.Int64(),
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.URLLifetime{
+		URL:     *toDomainURL(&row.Url),
+		EndedAt: row.EndedAt,
+		Reused:  row.Reused,
+	}, nil
+}
+
+// HoldsShortCode implements [domain.URLRepository].
+func (p *PostgresURLRepository) HoldsShortCode(ctx context.Context, url *domain.URL) (bool, error) {
+	return p.querier.URLHoldsShortCode(ctx, db.URLHoldsShortCodeParams{
+		ShortCode: url.ShortCode.String(),
+		CreatedAt: url.CreatedAt,
+	})
+}
+
+// ListShortCodesByUser implements [domain.URLRepository].
+func (p *PostgresURLRepository) ListShortCodesByUser(ctx context.Context, userID string) ([]domain.ShortCode, error) {
+	codes, err := p.querier.ListShortCodesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.ShortCode, len(codes))
+	for i, c := range codes {
+		result[i] = domain.ShortCode(c)
+	}
+
+	return result, nil
+}
+
+// GetByID implements [domain.URLRepository].
+func (p *PostgresURLRepository) GetByID(ctx context.Context, id domain.SnowflakeID, userID string) (*domain.URL, error) {
+	url, err := p.querier.GetURLByID(ctx, db.GetURLByIDParams{
+		ID:     id.Int64(),
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -47,23 +205,176 @@ func (p *PostgresURLRepository) GetActiveURLByShortCode(ctx context.Context, sho
 	return toDomainURL(&url), nil
 }
 
+// ListByIDs implements [domain.URLRepository].
+func (p *PostgresURLRepository) ListByIDs(ctx context.Context, userID string, ids []domain.SnowflakeID) ([]domain.URL, error) {
+	raw := make([]int64, len(ids))
+	for i, id := range ids {
+		raw[i] = id.Int64()
+	}
+
+	urls, err := p.querier.ListURLsByIDs(ctx, db.ListURLsByIDsParams{
+		UserID: userID,
+		Ids:    raw,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.URL, 0, len(urls))
+	for _, u := range urls {
+		result = append(result, *toDomainURL(&u))
+	}
+
+	return result, nil
+}
+
 // Create implements [domain.URLRepository].
 func (p *PostgresURLRepository) Create(ctx context.Context, url *domain.URL) error {
 	_, err := p.querier.CreateURL(ctx, db.CreateURLParams{
-		ID:          url.ID.Int64(),
-		ShortCode:   url.ShortCode.String(),
-		OriginalUrl: url.OriginalURL,
-		Title:       url.Title,
-		UserID:      url.UserID,
-		ExpiresAt:   url.ExpiresAt,
+		ID:             url.ID.Int64(),
+		ShortCode:      url.ShortCode.String(),
+		OriginalUrl:    url.OriginalURL,
+		Title:          url.Title,
+		Notes:          url.Notes,
+		UserID:         url.UserID,
+		ExpiresAt:      url.ExpiresAt,
+		RedirectStatus: toRedirectStatus(url.RedirectStatus),
+		ForwardQuery:   url.ForwardQuery,
+		ForwardPath:    url.ForwardPath,
+		PasswordHash:   toNullableString(url.PasswordHash),
+		MaxClicks:      toMaxClicks(url.MaxClicks),
+		ActiveFrom:     url.ActiveFrom,
+		FallbackUrl:    toNullableString(url.FallbackURL),
+		GeoTargets:     toTargetsJSON(url.GeoTargets),
+		DeviceTargets:  toTargetsJSON(url.DeviceTargets),
+		Variants:       toVariantsJSON(url.Variants),
+		StickyVariants: url.StickyVariants,
+	})
+	if err != nil {
+		return mapWriteError(err)
+	}
+
+	return nil
+}
+
+// Update implements [domain.URLRepository].
+func (p *PostgresURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	updated, err := p.querier.UpdateURL(ctx, db.UpdateURLParams{
+		ID:             url.ID.Int64(),
+		UserID:         url.UserID,
+		OriginalUrl:    url.OriginalURL,
+		Title:          url.Title,
+		Notes:          url.Notes,
+		ExpiresAt:      url.ExpiresAt,
+		Status:         url.Status,
+		RedirectStatus: toRedirectStatus(url.RedirectStatus),
+		ForwardQuery:   url.ForwardQuery,
+		ForwardPath:    url.ForwardPath,
+		PasswordHash:   toNullableString(url.PasswordHash),
+		MaxClicks:      toMaxClicks(url.MaxClicks),
+		ActiveFrom:     url.ActiveFrom,
+		FallbackUrl:    toNullableString(url.FallbackURL),
+		GeoTargets:     toTargetsJSON(url.GeoTargets),
+		DeviceTargets:  toTargetsJSON(url.DeviceTargets),
+		Variants:       toVariantsJSON(url.Variants),
+		StickyVariants: url.StickyVariants,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrURLNotFound
+	}
 	if err != nil {
-		return err
+		return mapWriteError(err)
 	}
 
+	*url = *toDomainURL(&updated)
+
 	return nil
 }
 
+// Delete implements [domain.URLRepository].
+func (p *PostgresURLRepository) Delete(ctx context.Context, id domain.SnowflakeID, userID string) (*domain.URL, error) {
+	url, err := p.querier.SoftDeleteURL(ctx, db.SoftDeleteURLParams{
+		ID:     id.Int64(),
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainURL(&url), nil
+}
+
+// Restore implements [domain.URLRepository].
+func (p *PostgresURLRepository) Restore(ctx context.Context, id domain.SnowflakeID, userID string, deletedAfter time.Time) (*domain.URL, error) {
+	url, err := p.querier.RestoreURL(ctx, db.RestoreURLParams{
+		ID:        id.Int64(),
+		UserID:    userID,
+		DeletedAt: &deletedAfter,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, mapWriteError(err)
+	}
+
+	return toDomainURL(&url), nil
+}
+
+// ListDeletedByUser implements [domain.URLRepository].
+func (p *PostgresURLRepository) ListDeletedByUser(ctx context.Context, userID string, deletedAfter time.Time) ([]domain.URL, error) {
+	urls, err := p.querier.ListDeletedURLs(ctx, db.ListDeletedURLsParams{
+		UserID:    userID,
+		DeletedAt: &deletedAfter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.URL, 0, len(urls))
+	for _, u := range urls {
+		result = append(result, *toDomainURL(&u))
+	}
+
+	return result, nil
+}
+
+// PurgeDeleted implements [domain.URLRepository].
+func (p *PostgresURLRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return p.querier.PurgeDeletedURLs(ctx, &deletedBefore)
+}
+
+// ExpireDue implements [domain.URLRepository].
+func (p *PostgresURLRepository) ExpireDue(ctx context.Context, limit int) ([]domain.URL, error) {
+	urls, err := p.querier.ExpireDueURLs(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.URL, 0, len(urls))
+	for _, u := range urls {
+		result = append(result, *toDomainURL(&u))
+	}
+
+	return result, nil
+}
+
+// Exhaust implements [domain.URLRepository].
+func (p *PostgresURLRepository) Exhaust(ctx context.Context, id domain.SnowflakeID) (*domain.URL, error) {
+	u, err := p.querier.ExhaustURL(ctx, id.Int64())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainURL(&u), nil
+}
+
 // CountByUser implements [domain.URLRepository].
 func (p *PostgresURLRepository) CountByUser(ctx context.Context, userID string) (int64, error) {
 	return p.querier.CountURLsByUser(ctx, userID)
@@ -75,16 +386,102 @@ func (p *PostgresURLRepository) CountActiveByUser(ctx context.Context, userID st
 }
 
 func toDomainURL(u *db.Url) *domain.URL {
-	return &domain.URL{
-		ID:          domain.SnowflakeID(u.ID),
-		OriginalURL: u.OriginalUrl,
-		ShortCode:   domain.ShortCode(u.ShortCode),
-		UserID:      u.UserID,
-		ExpiresAt:   u.ExpiresAt,
-		CreatedAt:   u.CreatedAt,
-		UpdatedAt:   u.UpdatedAt,
-		Status:      u.Status,
-		Title:       "",
-		Notes:       "",
+	url := &domain.URL{
+		ID:             domain.SnowflakeID(u.ID),
+		OriginalURL:    u.OriginalUrl,
+		ShortCode:      domain.ShortCode(u.ShortCode),
+		UserID:         u.UserID,
+		ExpiresAt:      u.ExpiresAt,
+		CreatedAt:      u.CreatedAt,
+		UpdatedAt:      u.UpdatedAt,
+		DeletedAt:      u.DeletedAt,
+		Status:         u.Status,
+		Title:          u.Title,
+		Notes:          u.Notes,
+		ForwardQuery:   u.ForwardQuery,
+		ForwardPath:    u.ForwardPath,
+		ActiveFrom:     u.ActiveFrom,
+		StickyVariants: u.StickyVariants,
+	}
+	if u.RedirectStatus != nil {
+		url.RedirectStatus = int(*u.RedirectStatus)
+	}
+	if u.PasswordHash != nil {
+		url.PasswordHash = *u.PasswordHash
+	}
+	if u.MaxClicks != nil {
+		url.MaxClicks = int(*u.MaxClicks)
+	}
+	if u.FallbackUrl != nil {
+		url.FallbackURL = *u.FallbackUrl
+	}
+	if u.GeoTargets != nil {
+		if err := json.Unmarshal(u.GeoTargets, &url.GeoTargets); err != nil {
+			slog.Error("Failed to decode geo targets", "short_code", u.ShortCode, "error", err)
+		}
+	}
+	if u.DeviceTargets != nil {
+		if err := json.Unmarshal(u.DeviceTargets, &url.DeviceTargets); err != nil {
+			slog.Error("Failed to decode device targets", "short_code", u.ShortCode, "error", err)
+		}
+	}
+	if u.Variants != nil {
+		if err := json.Unmarshal(u.Variants, &url.Variants); err != nil {
+			slog.Error("Failed to decode variants", "short_code", u.ShortCode, "error", err)
+		}
+	}
+	return url
+}
+
+func toRedirectStatus(status int) *int16 {
+	if status == 0 {
+		return nil
+	}
+	s := int16(status)
+	return &s
+}
+
+func toMaxClicks(n int) *int32 {
+	if n == 0 {
+		return nil
+	}
+	c := int32(n)
+	return &c
+}
+
+// toTargetsJSON encodes a targeting map for a JSONB column. Empty maps are
+// stored as NULL.
+func toTargetsJSON[V any](targets map[string]V) []byte {
+	if len(targets) == 0 {
+		return nil
+	}
+	// The value types are plain strings and structs of strings, which
+	// always marshal.
+	b, _ := json.Marshal(targets)
+	return b
+}
+
+// toVariantsJSON encodes variants for a JSONB column. No variants are stored
+// as NULL.
+func toVariantsJSON(variants []domain.Variant) []byte {
+	if len(variants) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(variants)
+	return b
+}
+
+func toNullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func mapWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return domain.ErrShortCodeConflict
 	}
+	return err
 }
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/SirNacou/refract/api/internal/db"
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/jackc/pgx/v5"
+)
+
+type PostgresUserSettingsRepository struct {
+	querier db.Querier
+}
+
+func NewPostgresUserSettingsRepository(querier db.Querier) domain.UserSettingsRepository {
+	return &PostgresUserSettingsRepository{
+		querier: querier,
+	}
+}
+
+// Get implements [domain.UserSettingsRepository].
+func (p *PostgresUserSettingsRepository) Get(ctx context.Context, userID string) (*domain.UserSettings, error) {
+	s, err := p.querier.GetUserSettings(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &domain.UserSettings{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainUserSettings(&s), nil
+}
+
+// Save implements [domain.UserSettingsRepository].
+func (p *PostgresUserSettingsRepository) Save(ctx context.Context, settings *domain.UserSettings) error {
+	s, err := p.querier.UpsertUserSettings(ctx, db.UpsertUserSettingsParams{
+		UserID:      settings.UserID,
+		FallbackUrl: toNullableString(settings.FallbackURL),
+	})
+	if err != nil {
+		return err
+	}
+
+	*settings = *toDomainUserSettings(&s)
+	return nil
+}
+
+func toDomainUserSettings(s *db.UserSetting) *domain.UserSettings {
+	settings := &domain.UserSettings{
+		UserID:    s.UserID,
+		UpdatedAt: s.UpdatedAt,
+	}
+	if s.FallbackUrl != nil {
+		settings.FallbackURL = *s.FallbackUrl
+	}
+	return settings
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/SirNacou/refract/api/internal/infrastructure/auth"
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// AdminMiddleware lets only the configured admin users through. It must run
+// after the auth middleware.
+type AdminMiddleware struct {
+	api    huma.API
+	admins map[string]bool
+}
+
+func NewAdminMiddleware(api huma.API, userIDs []string) *AdminMiddleware {
+	admins := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			admins[id] = true
+		}
+	}
+	return &AdminMiddleware{api: api, admins: admins}
+}
+
+func (am *AdminMiddleware) HandlerHuma(ctx huma.Context, next func(huma.Context)) {
+	userID, err := auth.GetUserIDFromContext(ctx.Context())
+	if err != nil {
+		_ = huma.WriteErr(am.api, ctx, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if !am.admins[userID] {
+		_ = huma.WriteErr(am.api, ctx, http.StatusForbidden, "Forbidden")
+		return
+	}
+
+	next(ctx)
+}
@@ -10,6 +10,9 @@ import (
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/SirNacou/refract/api/internal/config"
+	"github.com/SirNacou/refract/api/internal/features/clicks"
+	"github.com/SirNacou/refract/api/internal/features/exports"
+	"github.com/SirNacou/refract/api/internal/features/settings"
 	"github.com/SirNacou/refract/api/internal/features/urls"
 	"github.com/SirNacou/refract/api/internal/infrastructure/persistence"
 	"github.com/SirNacou/refract/api/internal/infrastructure/server/middleware"
@@ -72,6 +75,21 @@ func (r *Router) SetUp(ctx context.Context, db *persistence.DB, valkey valkeyasi
 		return err
 	}
 
+	if err = settings.NewModule(db, valkey, r.cfg).RegisterRoutes(grp); err != nil {
+		return err
+	}
+
+	if err = exports.NewModule(db, r.cfg).RegisterRoutes(grp); err != nil {
+		return err
+	}
+
+	admin := huma.NewGroup(grp, "/admin")
+	admin.UseMiddleware(middleware.NewAdminMiddleware(admin, r.cfg.AdminUserIDs).HandlerHuma)
+
+	if err = clicks.NewModule(valkey, r.cfg).RegisterRoutes(admin); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,88 @@
+package useragent
+
+import (
+	"strings"
+
+	"github.com/SirNacou/refract/api/internal/domain"
+	"github.com/mssola/useragent"
+)
+
+// Platform classifies a User-Agent for device targeting. Crawlers and mobile
+// systems other than iOS and Android return an empty string so they get the
+// default destination.
+func Platform(ua string) domain.Platform {
+	p := useragent.New(ua)
+	if p.Bot() {
+		return ""
+	}
+
+	switch platform := p.Platform(); {
+	case platform == "iPhone" || platform == "iPad" || platform == "iPod":
+		return domain.PlatformIOS
+	case strings.HasPrefix(p.OS(), "Android"):
+		return domain.PlatformAndroid
+	case p.Mobile():
+		return ""
+	default:
+		return domain.PlatformDesktop
+	}
+}
+
+// Device types stored with each click.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Details are the analytics dimensions derived from a User-Agent. Fields
+// are empty when the header is missing or unrecognised.
+type Details struct {
+	DeviceType     string
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Bot            bool
+}
+
+// Parse derives the analytics dimensions for a click.
+func Parse(ua string) Details {
+	if strings.TrimSpace(ua) == "" {
+		return Details{}
+	}
+
+	p := useragent.New(ua)
+	browser, browserVersion := p.Browser()
+	os := p.OSInfo()
+
+	d := Details{
+		Browser:        browser,
+		BrowserVersion: browserVersion,
+		OS:             os.Name,
+		OSVersion:      os.Version,
+		Bot:            p.Bot(),
+	}
+
+	platform := p.Platform()
+	isIOS := platform == "iPhone" || platform == "iPad" || platform == "iPod"
+	if isIOS {
+		d.OS = "iOS"
+	}
+
+	switch {
+	case d.Bot:
+		d.DeviceType = DeviceBot
+	case d.OS == "":
+		// Scripts and HTTP libraries carry no platform to classify.
+	case platform == "iPad" || (d.OS == "Android" && !strings.Contains(ua, "Mobile")):
+		d.DeviceType = DeviceTablet
+	case p.Mobile():
+		d.DeviceType = DeviceMobile
+	default:
+		d.DeviceType = DeviceDesktop
+	}
+
+	return d
+}
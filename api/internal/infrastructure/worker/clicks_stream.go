@@ -3,21 +3,33 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/SirNacou/refract/api/internal/config"
 	ingestclicks "github.com/SirNacou/refract/api/internal/features/clicks/ingest_clicks"
+	"github.com/SirNacou/refract/api/internal/infrastructure/deadletter"
 	"github.com/SirNacou/refract/api/internal/infrastructure/publisher"
 	"github.com/valkey-io/valkey-go"
 )
 
 type ClicksStreamWorker struct {
-	valkey   valkey.Client
-	handler  *ingestclicks.CommandHandler
-	cfg      *config.ValkeyConfig
-	stopChan chan error
+	valkey      valkey.Client
+	handler     *ingestclicks.CommandHandler
+	enricher    *Enricher
+	deadLetters *deadletter.Queue
+	cfg         *config.ValkeyConfig
+	stopChan    chan error
+
+	// lastFlushErr is why the last batch could not be ingested. It is the
+	// dead-letter reason of entries that decode fine.
+	lastFlushErr error
+	// claimCursor is where the next XAUTOCLAIM scan starts.
+	claimCursor string
 }
 
 type Batch struct {
@@ -25,7 +37,7 @@ type Batch struct {
 	IDs    []string
 }
 
-func NewClicksStreamWorker(ctx context.Context, valkey valkey.Client, handler *ingestclicks.CommandHandler, cfg *config.ValkeyConfig) (*ClicksStreamWorker, error) {
+func NewClicksStreamWorker(ctx context.Context, valkey valkey.Client, handler *ingestclicks.CommandHandler, enricher *Enricher, deadLetters *deadletter.Queue, cfg *config.ValkeyConfig) (*ClicksStreamWorker, error) {
 	cmd := valkey.B().XgroupCreate().Key(cfg.ClicksStreamKey).Group(cfg.ReadGroup).Id("0").Mkstream().Build()
 	err := valkey.Do(ctx, cmd).Error()
 	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
@@ -33,10 +45,13 @@ func NewClicksStreamWorker(ctx context.Context, valkey valkey.Client, handler *i
 	}
 
 	return &ClicksStreamWorker{
-		valkey:   valkey,
-		handler:  handler,
-		cfg:      cfg,
-		stopChan: make(chan error),
+		valkey:      valkey,
+		handler:     handler,
+		enricher:    enricher,
+		deadLetters: deadLetters,
+		cfg:         cfg,
+		stopChan:    make(chan error),
+		claimCursor: "0-0",
 	}, nil
 }
 
@@ -75,11 +90,16 @@ func (w *ClicksStreamWorker) loop(ctx context.Context) error {
 		}
 
 		select {
-		case <-wCtx.Done():
+		case <-ctx.Done():
+			// ctx is already done, so the last flush and leaving the group
+			// get their own deadline.
+			stopCtx, stopCancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second*5)
+			defer stopCancel()
 			if len(batch.Clicks) > 0 {
-				w.flush(wCtx, batch)
+				w.flush(stopCtx, batch)
 			}
-			return wCtx.Err()
+			w.leaveGroup(stopCtx)
+			return ctx.Err()
 		case <-ticker.C:
 			if len(batch.Clicks) > 0 {
 				w.flush(wCtx, batch)
@@ -126,7 +146,23 @@ func (w *ClicksStreamWorker) readNewClicks(ctx context.Context, batch *Batch) er
 	return nil
 }
 
+// readPendingClicks re-reads entries that were delivered but never acked,
+// including those just claimed from stale consumers. Entries delivered
+// MaxDeliveries times are moved to the dead-letter stream instead of being
+// retried forever.
 func (w *ClicksStreamWorker) readPendingClicks(ctx context.Context, batch *Batch) error {
+	if err := w.claimStale(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to claim stale clicks", "error", err)
+	} else if err := w.removeDeadConsumers(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to remove dead consumers", "error", err)
+	}
+
+	// Delivery counts are taken before the read below increments them.
+	deliveries, err := w.pendingDeliveries(ctx)
+	if err != nil {
+		return err
+	}
+
 	pendingCmd := w.valkey.B().Xreadgroup().
 		Group(w.cfg.ReadGroup, w.cfg.Consumer).
 		Count(100).
@@ -140,7 +176,25 @@ func (w *ClicksStreamWorker) readPendingClicks(ctx context.Context, batch *Batch
 		return err
 	} else if pendingStream, ok := pendingRes[w.cfg.ClicksStreamKey]; ok && len(pendingStream) > 0 {
 		slog.Info("Found pending messages", "count", len(pendingStream))
-		items, ids, err := w.processBatch(ctx, pendingStream)
+
+		// Entries read recently may still be waiting in the batch.
+		inBatch := make(map[string]bool, len(batch.IDs))
+		for _, id := range batch.IDs {
+			inBatch[id] = true
+		}
+
+		retry := make([]valkey.XRangeEntry, 0, len(pendingStream))
+		for _, e := range pendingStream {
+			switch {
+			case inBatch[e.ID]:
+			case w.cfg.MaxDeliveries > 0 && deliveries[e.ID] >= int64(w.cfg.MaxDeliveries):
+				w.deadLetter(ctx, e, deliveries[e.ID])
+			default:
+				retry = append(retry, e)
+			}
+		}
+
+		items, ids, err := w.processBatch(ctx, retry)
 		if err == nil {
 			batch.Clicks = append(batch.Clicks, items...)
 			batch.IDs = append(batch.IDs, ids...)
@@ -150,32 +204,239 @@ func (w *ClicksStreamWorker) readPendingClicks(ctx context.Context, batch *Batch
 	return nil
 }
 
-func (w *ClicksStreamWorker) processBatch(ctx context.Context, batch []valkey.XRangeEntry) (clicks []ingestclicks.Click, ids []string, err error) {
-	for _, v := range batch {
-		data, ok := v.FieldValues["data"]
-		if !ok {
+// claimStale moves entries that sat pending for ClaimMinIdle, usually in
+// the list of a replica that died, to this consumer. JUSTID keeps their
+// delivery counts, so the pending read below retries and dead-letters them
+// like our own. One page is claimed per call; the scan resumes from the
+// cursor on the next call.
+func (w *ClicksStreamWorker) claimStale(ctx context.Context) error {
+	cmd := w.valkey.B().Xautoclaim().
+		Key(w.cfg.ClicksStreamKey).
+		Group(w.cfg.ReadGroup).
+		Consumer(w.cfg.Consumer).
+		MinIdleTime(strconv.FormatInt(w.cfg.ClaimMinIdle.Milliseconds(), 10)).
+		Start(w.claimCursor).
+		Count(100).
+		Justid().
+		Build()
+
+	// The reply is [next cursor, claimed IDs, deleted IDs].
+	res, err := w.valkey.Do(ctx, cmd).ToArray()
+	if err != nil {
+		return err
+	}
+	if len(res) < 2 {
+		return fmt.Errorf("unexpected XAUTOCLAIM reply of %d elements", len(res))
+	}
+
+	cursor, err := res[0].ToString()
+	if err != nil {
+		return err
+	}
+	w.claimCursor = cursor
+
+	claimed, err := res[1].AsStrSlice()
+	if err != nil {
+		return err
+	}
+	if len(claimed) > 0 {
+		slog.InfoContext(ctx, "Claimed stale clicks", "count", len(claimed), "consumer", w.cfg.Consumer)
+	}
+	return nil
+}
+
+// removeDeadConsumers deletes the consumers of replicas that died without
+// leaving the group. A consumer idle for ClaimMinIdle with nothing pending,
+// its entries having been claimed above, has nothing left to lose. Replicas
+// that are alive read every few seconds and are never that idle.
+func (w *ClicksStreamWorker) removeDeadConsumers(ctx context.Context) error {
+	cmd := w.valkey.B().XinfoConsumers().Key(w.cfg.ClicksStreamKey).Group(w.cfg.ReadGroup).Build()
+	consumers, err := w.valkey.Do(ctx, cmd).ToArray()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range consumers {
+		info, err := c.AsMap()
+		if err != nil {
+			return err
+		}
+		nameMsg, pendingMsg, idleMsg := info["name"], info["pending"], info["idle"]
+		name, err := nameMsg.ToString()
+		if err != nil || name == w.cfg.Consumer {
+			continue
+		}
+		pending, err := pendingMsg.AsInt64()
+		if err != nil || pending > 0 {
+			continue
+		}
+		idle, err := idleMsg.AsInt64()
+		if err != nil || time.Duration(idle)*time.Millisecond < w.cfg.ClaimMinIdle {
 			continue
 		}
 
+		delCmd := w.valkey.B().XgroupDelconsumer().
+			Key(w.cfg.ClicksStreamKey).
+			Group(w.cfg.ReadGroup).
+			Consumername(name).
+			Build()
+		if err := w.valkey.Do(ctx, delCmd).Error(); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "Removed dead consumer", "consumer", name)
+	}
+	return nil
+}
+
+// leaveGroup deletes this consumer from the group so stopped replicas do
+// not pile up. Deleting a consumer drops its pending entries, so one that
+// still has some is kept for the other replicas to claim.
+func (w *ClicksStreamWorker) leaveGroup(ctx context.Context) {
+	cmd := w.valkey.B().Xpending().
+		Key(w.cfg.ClicksStreamKey).
+		Group(w.cfg.ReadGroup).
+		Start("-").
+		End("+").
+		Count(1).
+		Consumer(w.cfg.Consumer).
+		Build()
+
+	pending, err := w.valkey.Do(ctx, cmd).ToArray()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check pending clicks before leaving group", "error", err)
+		return
+	}
+	if len(pending) > 0 {
+		slog.InfoContext(ctx, "Keeping consumer with pending clicks for other replicas to claim", "consumer", w.cfg.Consumer)
+		return
+	}
+
+	delCmd := w.valkey.B().XgroupDelconsumer().
+		Key(w.cfg.ClicksStreamKey).
+		Group(w.cfg.ReadGroup).
+		Consumername(w.cfg.Consumer).
+		Build()
+
+	if err := w.valkey.Do(ctx, delCmd).Error(); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete consumer", "consumer", w.cfg.Consumer, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "Left clicks read group", "consumer", w.cfg.Consumer)
+}
+
+// pendingDeliveries returns how many times each of the oldest pending
+// entries of this consumer was delivered, covering the same entries as the
+// pending read.
+func (w *ClicksStreamWorker) pendingDeliveries(ctx context.Context) (map[string]int64, error) {
+	cmd := w.valkey.B().Xpending().
+		Key(w.cfg.ClicksStreamKey).
+		Group(w.cfg.ReadGroup).
+		Start("-").
+		End("+").
+		Count(100).
+		Consumer(w.cfg.Consumer).
+		Build()
+
+	entries, err := w.valkey.Do(ctx, cmd).ToArray()
+	if err != nil {
+		return nil, err
+	}
+
+	// Each entry is [id, consumer, idle ms, deliveries].
+	deliveries := make(map[string]int64, len(entries))
+	for _, e := range entries {
+		fields, err := e.ToArray()
+		if err != nil || len(fields) < 4 {
+			continue
+		}
+		id, err := fields[0].ToString()
+		if err != nil {
+			continue
+		}
+		deliveries[id], _ = fields[3].AsInt64()
+	}
+	return deliveries, nil
+}
+
+// deadLetter moves the entry to the dead-letter stream. It stays pending
+// and is tried again when that fails.
+func (w *ClicksStreamWorker) deadLetter(ctx context.Context, entry valkey.XRangeEntry, deliveries int64) {
+	reason := "not ingested after max deliveries"
+	if _, err := decodeClick(entry); err != nil {
+		reason = err.Error()
+	} else if w.lastFlushErr != nil {
+		reason = "ingest failed: " + w.lastFlushErr.Error()
+	}
+
+	err := w.deadLetters.Add(ctx, &deadletter.Letter{
+		OriginalID: entry.ID,
+		Fields:     entry.FieldValues,
+		Reason:     reason,
+		Deliveries: deliveries,
+		FailedAt:   time.Now(),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to dead-letter click", "id", entry.ID, "error", err)
+		return
+	}
+
+	ackCmd := w.valkey.B().Xack().Key(w.cfg.ClicksStreamKey).Group(w.cfg.ReadGroup).Id(entry.ID).Build()
+	if err := w.valkey.Do(ctx, ackCmd).Error(); err != nil {
+		slog.ErrorContext(ctx, "Failed to acknowledge dead-lettered click", "id", entry.ID, "error", err)
+	}
+
+	slog.WarnContext(ctx, "Moved click to dead-letter stream", "id", entry.ID, "deliveries", deliveries, "reason", reason)
+}
+
+func decodeClick(entry valkey.XRangeEntry) (*publisher.ClicksPublisherRequest, error) {
+	data, ok := entry.FieldValues["data"]
+	if !ok {
+		return nil, errors.New("missing data field")
+	}
+
+	req := &publisher.ClicksPublisherRequest{}
+	if err := json.Unmarshal([]byte(data), req); err != nil {
+		return nil, fmt.Errorf("invalid click data: %w", err)
+	}
+	return req, nil
+}
+
+// processBatch decodes and enriches the entries. Entries that do not decode
+// stay pending until they are dead-lettered.
+func (w *ClicksStreamWorker) processBatch(ctx context.Context, batch []valkey.XRangeEntry) (clicks []ingestclicks.Click, ids []string, err error) {
+	for _, v := range batch {
 		slog.Info("Message", "id", v.ID, "value", v.FieldValues)
 
-		req := &publisher.ClicksPublisherRequest{}
-		err := json.Unmarshal([]byte(data), &req)
+		req, err := decodeClick(v)
 		if err != nil {
-			slog.ErrorContext(ctx, "Failed to unmarshal click", "error", err)
+			slog.ErrorContext(ctx, "Failed to decode click", "id", v.ID, "error", err)
 			continue
 		}
 
-		clicks = append(clicks, ingestclicks.Click{
+		click := ingestclicks.Click{
+			URLID:     req.URLID,
+			UserID:    req.UserID,
 			ShortCode: req.ShortCode,
 			ClickedAt: req.ClickedAt,
 			IPAddress: req.IPAddress,
 			UserAgent: req.UserAgent,
 			Referer:   req.Referer,
-		})
+			Variant:   req.Variant,
+
+			UTMSource:   req.UTMSource,
+			UTMMedium:   req.UTMMedium,
+			UTMCampaign: req.UTMCampaign,
+			UTMTerm:     req.UTMTerm,
+			UTMContent:  req.UTMContent,
+		}
+		w.enricher.Enrich(&click)
+
+		clicks = append(clicks, click)
 		ids = append(ids, v.ID)
 	}
 
+	w.enricher.FlagBursts(ctx, clicks, ids)
+
 	return clicks, ids, nil
 }
 
@@ -186,8 +447,14 @@ func (w *ClicksStreamWorker) flush(ctx context.Context, batch *Batch) error {
 
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to handle clicks batch", "error", err)
+		// The entries stay pending and come back through readPendingClicks,
+		// which dead-letters them once they have failed too often.
+		w.lastFlushErr = err
+		batch.Clicks = []ingestclicks.Click{}
+		batch.IDs = []string{}
 		return err
 	}
+	w.lastFlushErr = nil
 
 	ackCmd := w.valkey.B().Xack().
 		Key(w.cfg.ClicksStreamKey).
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+
+	ingestclicks "github.com/SirNacou/refract/api/internal/features/clicks/ingest_clicks"
+	"github.com/SirNacou/refract/api/internal/infrastructure/botdetect"
+	"github.com/SirNacou/refract/api/internal/infrastructure/geoip"
+	"github.com/SirNacou/refract/api/internal/infrastructure/referrer"
+	"github.com/SirNacou/refract/api/internal/infrastructure/useragent"
+)
+
+// Enricher fills the derived click dimensions before a batch is written to
+// ClickHouse.
+type Enricher struct {
+	geo         *geoip.Reader
+	asn         *geoip.Reader
+	botRanges   botdetect.Ranges
+	burstDetect *botdetect.BurstDetector
+}
+
+// NewEnricher creates an Enricher. geo, asn and bursts may be nil, in which
+// case clicks get no location or network and bursts are not detected.
+func NewEnricher(geo, asn *geoip.Reader, botRanges botdetect.Ranges, bursts *botdetect.BurstDetector) *Enricher {
+	return &Enricher{geo: geo, asn: asn, botRanges: botRanges, burstDetect: bursts}
+}
+
+func (e *Enricher) Enrich(click *ingestclicks.Click) {
+	ua := useragent.Parse(click.UserAgent)
+	click.DeviceType = ua.DeviceType
+	click.Browser = ua.Browser
+	click.BrowserVersion = ua.BrowserVersion
+	click.OS = ua.OS
+	click.OSVersion = ua.OSVersion
+
+	switch {
+	case ua.Bot || botdetect.IsBotUserAgent(click.UserAgent):
+		click.IsBot = true
+		click.BotReason = botdetect.ReasonUserAgent
+	case e.botRanges.Contains(click.IPAddress):
+		click.IsBot = true
+		click.BotReason = botdetect.ReasonIPRange
+	}
+
+	click.RefererDomain = referrer.Domain(click.Referer)
+	click.Channel = referrer.Channel(click.RefererDomain, click.UTMMedium)
+
+	if e.geo != nil {
+		loc := e.geo.Location(click.IPAddress)
+		click.Country = loc.Country
+		click.Region = loc.Region
+		click.City = loc.City
+	}
+
+	if e.asn != nil {
+		network := e.asn.Network(click.IPAddress)
+		click.ASN = network.ASN
+		click.ASOrg = network.Organization
+	}
+}
+
+// FlagBursts marks clicks as bots once their address goes over the burst
+// limit. ids are the stream IDs of clicks, so entries read again after a
+// failed flush are not counted twice. Clicks up to the limit stay human.
+// Counting failures are logged and leave the clicks as they are.
+func (e *Enricher) FlagBursts(ctx context.Context, clicks []ingestclicks.Click, ids []string) {
+	if e.burstDetect == nil {
+		return
+	}
+
+	byIP := make(map[string][]int)
+	for i, c := range clicks {
+		byIP[c.IPAddress] = append(byIP[c.IPAddress], i)
+	}
+
+	for ip, idx := range byIP {
+		streamIDs := make([]string, len(idx))
+		for n, i := range idx {
+			streamIDs[n] = ids[i]
+		}
+
+		over, err := e.burstDetect.OverLimit(ctx, ip, streamIDs)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to count click burst", "ip", ip, "error", err)
+			continue
+		}
+
+		for _, i := range idx {
+			if over[ids[i]] && !clicks[i].IsBot {
+				clicks[i].IsBot = true
+				clicks[i].BotReason = botdetect.ReasonBurst
+			}
+		}
+	}
+}
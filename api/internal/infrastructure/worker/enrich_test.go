@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"net/netip"
+	"testing"
+
+	ingestclicks "github.com/SirNacou/refract/api/internal/features/clicks/ingest_clicks"
+	"github.com/SirNacou/refract/api/internal/infrastructure/botdetect"
+)
+
+// dimensions are the click fields derived from the User-Agent and address.
+type dimensions struct {
+	DeviceType, Browser, BrowserVersion, OS, OSVersion string
+	IsBot                                              bool
+	BotReason                                          string
+}
+
+func TestEnrichUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want dimensions
+	}{
+		{
+			name: "iPhone",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+			want: dimensions{DeviceType: "mobile", Browser: "Safari", BrowserVersion: "17.4", OS: "iOS", OSVersion: "17.4"},
+		},
+		{
+			name: "iPad",
+			ua:   "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+			want: dimensions{DeviceType: "tablet", Browser: "Safari", BrowserVersion: "16.6", OS: "iOS", OSVersion: "16.6"},
+		},
+		{
+			name: "Android phone",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36",
+			want: dimensions{DeviceType: "mobile", Browser: "Chrome", BrowserVersion: "124.0.6367.82", OS: "Android", OSVersion: "14"},
+		},
+		{
+			name: "Android tablet",
+			ua:   "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.6312.118 Safari/537.36",
+			want: dimensions{DeviceType: "tablet", Browser: "Chrome", BrowserVersion: "123.0.6312.118", OS: "Android", OSVersion: "13"},
+		},
+		{
+			name: "Edge on Windows",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.51",
+			want: dimensions{DeviceType: "desktop", Browser: "Edge", BrowserVersion: "124.0.2478.51", OS: "Windows", OSVersion: "10"},
+		},
+		{
+			name: "Firefox on macOS",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:125.0) Gecko/20100101 Firefox/125.0",
+			want: dimensions{DeviceType: "desktop", Browser: "Firefox", BrowserVersion: "125.0", OS: "Mac OS X", OSVersion: "10.15"},
+		},
+		{
+			name: "crawler",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			want: dimensions{DeviceType: "bot", Browser: "Googlebot", BrowserVersion: "2.1", IsBot: true, BotReason: botdetect.ReasonUserAgent},
+		},
+		{
+			// Libraries carry no platform, so they get no device type.
+			name: "HTTP library",
+			ua:   "curl/8.5.0",
+			want: dimensions{Browser: "curl", BrowserVersion: "8.5.0", IsBot: true, BotReason: botdetect.ReasonUserAgent},
+		},
+		{
+			name: "missing",
+			ua:   "",
+			want: dimensions{IsBot: true, BotReason: botdetect.ReasonUserAgent},
+		},
+	}
+
+	e := NewEnricher(nil, nil, nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ingestclicks.Click{UserAgent: tt.ua, IPAddress: "203.0.113.7"}
+			e.Enrich(&c)
+			assertDimensions(t, c, tt.want)
+		})
+	}
+}
+
+// A browser on a crawler network is a bot, a crawler User-Agent keeps its
+// own reason.
+func TestEnrichIPRange(t *testing.T) {
+	e := NewEnricher(nil, nil, botdetect.Ranges{netip.MustParsePrefix("66.249.64.0/19")}, nil)
+
+	c := ingestclicks.Click{
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		IPAddress: "66.249.66.1",
+	}
+	e.Enrich(&c)
+	assertDimensions(t, c, dimensions{DeviceType: "desktop", Browser: "Chrome", BrowserVersion: "124.0.0.0", OS: "Windows", OSVersion: "10", IsBot: true, BotReason: botdetect.ReasonIPRange})
+
+	c = ingestclicks.Click{UserAgent: "curl/8.5.0", IPAddress: "66.249.66.1"}
+	e.Enrich(&c)
+	if c.BotReason != botdetect.ReasonUserAgent {
+		t.Errorf("BotReason = %q, want %q", c.BotReason, botdetect.ReasonUserAgent)
+	}
+}
+
+func assertDimensions(t *testing.T, c ingestclicks.Click, want dimensions) {
+	t.Helper()
+
+	got := dimensions{c.DeviceType, c.Browser, c.BrowserVersion, c.OS, c.OSVersion, c.IsBot, c.BotReason}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+)
+
+// PeriodicJob runs fn every interval until the context passed to Start is
+// cancelled. Failed runs are logged and retried on the next tick.
+type PeriodicJob struct {
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	fn       func(ctx context.Context) error
+	stopChan chan error
+
+	lock      valkey.Client
+	lockKey   string
+	lockOwner string
+}
+
+func NewPeriodicJob(name string, interval time.Duration, fn func(ctx context.Context) error) *PeriodicJob {
+	return &PeriodicJob{
+		name:     name,
+		interval: interval,
+		timeout:  interval,
+		fn:       fn,
+		stopChan: make(chan error, 1),
+	}
+}
+
+// WithTimeout lets a run take up to timeout instead of the interval. Ticks
+// that pass while a run is still going are skipped.
+func (j *PeriodicJob) WithTimeout(timeout time.Duration) *PeriodicJob {
+	j.timeout = timeout
+	return j
+}
+
+// WithLock runs the job on one replica per interval. The replica that takes
+// the lock key runs it, the others skip the tick. The lock is never
+// released but expires after the interval, so a replica that dies while
+// holding it only delays the next run. keyPattern may contain {job}.
+func (j *PeriodicJob) WithLock(client valkey.Client, keyPattern, owner string) *PeriodicJob {
+	j.lock = client
+	j.lockKey = strings.ReplaceAll(keyPattern, "{job}", j.name)
+	j.lockOwner = owner
+	return j
+}
+
+func (j *PeriodicJob) Start(ctx context.Context) error {
+	go func() {
+		j.stopChan <- j.loop(ctx)
+	}()
+
+	return nil
+}
+
+func (j *PeriodicJob) Stop(ctx context.Context) error {
+	select {
+	case err := <-j.stopChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (j *PeriodicJob) loop(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *PeriodicJob) run(ctx context.Context) {
+	runCtx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+
+	if j.lock != nil {
+		ok, err := j.acquire(runCtx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to take periodic job lock", "job", j.name, "error", err)
+			return
+		}
+		if !ok {
+			return
+		}
+	}
+
+	if err := j.fn(runCtx); err != nil {
+		slog.ErrorContext(ctx, "Periodic job failed", "job", j.name, "error", err)
+	}
+}
+
+func (j *PeriodicJob) acquire(ctx context.Context) (bool, error) {
+	cmd := j.lock.B().Set().Key(j.lockKey).Value(j.lockOwner).Nx().PxMilliseconds(j.interval.Milliseconds()).Build()
+	err := j.lock.Do(ctx, cmd).Error()
+	if valkey.IsValkeyNil(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,57 @@
+-- name: CreateExport :one
+INSERT INTO exports (id, user_id, url_id, short_code, format, range_from, range_to) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: GetExport :one
+SELECT  *
+FROM exports
+WHERE id = $1
+AND user_id = $2;
+
+-- name: ListExportsByUser :many
+SELECT  *
+FROM exports
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: ClaimExport :one
+-- Takes the oldest pending export, or a running one started before
+-- stale_before whose worker likely died. SKIP LOCKED lets several workers
+-- claim without blocking each other.
+UPDATE exports
+SET status = 'running',
+    started_at = NOW()
+WHERE id = (
+    SELECT id
+    FROM exports
+    WHERE status = 'pending'
+    OR (status = 'running' AND started_at < sqlc.arg(stale_before))
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteExport :one
+UPDATE exports
+SET status = 'completed',
+    file_name = $2,
+    size_bytes = $3,
+    completed_at = NOW(),
+    expires_at = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: FailExport :one
+UPDATE exports
+SET status = 'failed',
+    error = $2,
+    completed_at = NOW(),
+    expires_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteExpiredExports :many
+DELETE FROM exports
+WHERE expires_at <= NOW()
+RETURNING *;
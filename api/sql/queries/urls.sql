@@ -1,25 +1,241 @@
 -- name: ListURLs :many
+-- Keyset pagination over idx_urls_user_id_created_at, newest first.
 SELECT  *
 FROM urls
-WHERE user_id = $1
-ORDER BY created_at DESC;
+WHERE user_id = sqlc.arg(user_id)
+AND deleted_at IS NULL
+AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+AND (sqlc.narg(expires_after)::timestamptz IS NULL OR expires_at >= sqlc.narg(expires_after))
+AND (sqlc.narg(expires_before)::timestamptz IS NULL OR expires_at < sqlc.narg(expires_before))
+AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: ListURLsAsc :many
+-- Same as ListURLs, oldest first.
+SELECT  *
+FROM urls
+WHERE user_id = sqlc.arg(user_id)
+AND deleted_at IS NULL
+AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+AND (sqlc.narg(expires_after)::timestamptz IS NULL OR expires_at >= sqlc.narg(expires_after))
+AND (sqlc.narg(expires_before)::timestamptz IS NULL OR expires_at < sqlc.narg(expires_before))
+AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::bigint))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_size);
 
 -- name: GetActiveURLByShortCode :one 
 SELECT  *
 FROM urls
 WHERE short_code = $1
-AND status = 'active';
+AND status = 'active'
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetRedirectURLByShortCode :one
+-- Resolves a short code for the redirector whatever the link status, so
+-- inactive links can still send visitors to their fallback. The active row
+-- wins when an alias was reused.
+SELECT  sqlc.embed(urls),
+    user_settings.fallback_url AS user_fallback_url
+FROM urls
+LEFT JOIN user_settings ON user_settings.user_id = urls.user_id
+WHERE urls.short_code = $1
+AND urls.deleted_at IS NULL
+ORDER BY urls.status = 'active' DESC, urls.updated_at DESC
+LIMIT 1;
+
+-- name: ListShortCodesByUser :many
+SELECT short_code
+FROM urls
+WHERE user_id = $1
+AND deleted_at IS NULL;
 
 -- name: CreateURL :one 
-INSERT INTO urls (id, short_code, original_url, title, user_id, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+INSERT INTO urls (id, short_code, original_url, title, notes, user_id, expires_at, redirect_status, forward_query, forward_path, password_hash, max_clicks, active_from, fallback_url, geo_targets, device_targets, variants, sticky_variants) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING *;
 
 -- name: CountURLsByUser :one
 SELECT COUNT(*)
 FROM urls
-WHERE user_id = $1;
+WHERE user_id = $1
+AND deleted_at IS NULL;
 
 -- name: CountActiveURLsByUser :one
 SELECT COUNT(*)
 FROM urls
 WHERE user_id = $1
-AND status = 'active';
\ No newline at end of file
+AND status = 'active'
+AND deleted_at IS NULL
+AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetURLByID :one
+SELECT  *
+FROM urls
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL;
+
+-- name: UpdateURL :one
+UPDATE urls
+SET original_url = $3,
+    title = $4,
+    notes = $5,
+    expires_at = $6,
+    status = $7,
+    redirect_status = $8,
+    forward_query = $9,
+    forward_path = $10,
+    password_hash = $11,
+    max_clicks = $12,
+    active_from = $13,
+    fallback_url = $14,
+    geo_targets = $15,
+    device_targets = $16,
+    variants = $17,
+    sticky_variants = $18,
+    updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SoftDeleteURL :one
+UPDATE urls
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreURL :one
+UPDATE urls
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND deleted_at > $3
+RETURNING *;
+
+-- name: ListDeletedURLs :many
+SELECT  *
+FROM urls
+WHERE user_id = $1
+AND deleted_at > $2
+ORDER BY deleted_at DESC;
+
+-- name: PurgeDeletedURLs :execrows
+DELETE FROM urls
+WHERE deleted_at <= $1;
+
+-- name: SearchURLs :many
+-- Matches substrings via ILIKE and typos via trigram word similarity, both
+-- served by idx_urls_search_trgm.
+SELECT  sqlc.embed(urls),
+    word_similarity(sqlc.arg(query)::text, title || ' ' || original_url || ' ' || (short_code COLLATE "default"))::real AS rank
+FROM urls
+WHERE user_id = sqlc.arg(user_id)
+AND deleted_at IS NULL
+AND (
+    (title || ' ' || original_url || ' ' || (short_code COLLATE "default")) ILIKE sqlc.arg(pattern)::text
+    OR sqlc.arg(query)::text <% (title || ' ' || original_url || ' ' || (short_code COLLATE "default"))
+)
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT sqlc.arg(page_size)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ExpireDueURLs :many
+-- Flips a batch of active links past their expires_at to 'expired'.
+-- SKIP LOCKED lets several sweepers run without blocking each other.
+UPDATE urls
+SET status = 'expired',
+    updated_at = NOW()
+WHERE id IN (
+    SELECT id
+    FROM urls
+    WHERE status = 'active'
+    AND deleted_at IS NULL
+    AND expires_at <= NOW()
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ExhaustURL :one
+-- Flips an active link that reached its click cap to 'expired'.
+UPDATE urls
+SET status = 'expired',
+    updated_at = NOW()
+WHERE id = $1
+AND status = 'active'
+AND deleted_at IS NULL
+RETURNING *;
+
+-- name: ListURLLifetimesByUser :many
+-- Lists the links of a user with the span each held its short code, for
+-- scoping click analytics. ended_at is when a newer link took the short
+-- code over and reused whether an older one held it before. Deleted links
+-- count as holders, their clicks were recorded while they held the code.
+SELECT  sqlc.embed(urls),
+    successor.created_at AS ended_at,
+    EXISTS (
+        SELECT 1
+        FROM urls earlier
+        WHERE earlier.short_code = urls.short_code
+        AND earlier.created_at < urls.created_at
+    ) AS reused
+FROM urls
+LEFT JOIN LATERAL (
+    SELECT created_at
+    FROM urls later
+    WHERE later.short_code = urls.short_code
+    AND later.created_at > urls.created_at
+    ORDER BY later.created_at
+    LIMIT 1
+) successor ON TRUE
+WHERE urls.user_id = $1
+AND urls.deleted_at IS NULL;
+
+-- name: GetURLLifetime :one
+-- Same as ListURLLifetimesByUser for one link.
+SELECT  sqlc.embed(urls),
+    successor.created_at AS ended_at,
+    EXISTS (
+        SELECT 1
+        FROM urls earlier
+        WHERE earlier.short_code = urls.short_code
+        AND earlier.created_at < urls.created_at
+    ) AS reused
+FROM urls
+LEFT JOIN LATERAL (
+    SELECT created_at
+    FROM urls later
+    WHERE later.short_code = urls.short_code
+    AND later.created_at > urls.created_at
+    ORDER BY later.created_at
+    LIMIT 1
+) successor ON TRUE
+WHERE urls.id = $1
+AND urls.user_id = $2
+AND urls.deleted_at IS NULL;
+
+-- name: URLHoldsShortCode :one
+-- Whether no newer link took the short code over. Unlike GetURLLifetime it
+-- also answers for deleted links.
+SELECT NOT EXISTS (
+    SELECT 1
+    FROM urls later
+    WHERE later.short_code = $1
+    AND later.created_at > $2
+) AS holds;
+
+-- name: ListURLsByIDs :many
+SELECT *
+FROM urls
+WHERE user_id = sqlc.arg(user_id)
+AND id = ANY(sqlc.arg(ids)::bigint[])
+AND deleted_at IS NULL;
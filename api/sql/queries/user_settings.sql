@@ -0,0 +1,11 @@
+-- name: GetUserSettings :one
+SELECT  *
+FROM user_settings
+WHERE user_id = $1;
+
+-- name: UpsertUserSettings :one
+INSERT INTO user_settings (user_id, fallback_url) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET fallback_url = EXCLUDED.fallback_url,
+    updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,2 @@
+ALTER TABLE urls
+DROP COLUMN notes;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE urls
+ADD COLUMN notes TEXT NOT NULL DEFAULT '';
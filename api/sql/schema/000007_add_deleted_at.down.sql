@@ -0,0 +1,9 @@
+DROP INDEX idx_urls_deleted_at;
+
+DROP INDEX idx_urls_active_short_code;
+
+CREATE UNIQUE INDEX idx_urls_active_short_code ON urls (short_code)
+WHERE status = 'active';
+
+ALTER TABLE urls
+DROP COLUMN deleted_at;
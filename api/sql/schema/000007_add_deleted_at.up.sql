@@ -0,0 +1,12 @@
+ALTER TABLE urls
+ADD COLUMN deleted_at TIMESTAMPTZ; -- Nullable: NULL means "not in trash"
+
+-- Deleted links release their short code so it can be reused.
+DROP INDEX idx_urls_active_short_code;
+
+CREATE UNIQUE INDEX idx_urls_active_short_code ON urls (short_code)
+WHERE status = 'active' AND deleted_at IS NULL;
+
+-- Speeds up the trash listing and the retention purge.
+CREATE INDEX idx_urls_deleted_at ON urls (deleted_at)
+WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Trigram index over everything the search box matches against.
+-- short_code is collated "C", so it is re-collated to avoid a collation
+-- conflict in the concatenation. SearchURLs must use the same expression.
+CREATE INDEX idx_urls_search_trgm ON urls
+USING GIN ((title || ' ' || original_url || ' ' || (short_code COLLATE "default")) gin_trgm_ops);
@@ -0,0 +1,3 @@
+-- HTTP status used when redirecting. NULL falls back to the configured default.
+ALTER TABLE urls
+ADD COLUMN redirect_status SMALLINT CHECK (redirect_status IN (301, 302, 307, 308));
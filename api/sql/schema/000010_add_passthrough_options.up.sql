@@ -0,0 +1,6 @@
+-- Redirect passthrough options.
+-- forward_query merges the visitor's query string into the destination.
+-- forward_path appends extra path segments (/{short_code}/rest) to it.
+ALTER TABLE urls
+ADD COLUMN forward_query BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN forward_path BOOLEAN NOT NULL DEFAULT false;
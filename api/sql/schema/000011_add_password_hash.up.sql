@@ -0,0 +1,3 @@
+-- bcrypt hash of the link password. NULL means the link is public.
+ALTER TABLE urls
+ADD COLUMN password_hash TEXT;
@@ -0,0 +1,3 @@
+-- Number of redirects after which the link stops working. NULL means no cap.
+ALTER TABLE urls
+ADD COLUMN max_clicks INTEGER CHECK (max_clicks > 0);
@@ -0,0 +1,2 @@
+ALTER TABLE urls
+DROP COLUMN active_from;
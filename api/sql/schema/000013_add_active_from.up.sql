@@ -0,0 +1,3 @@
+-- Links with active_from in the future are scheduled and do not redirect yet.
+ALTER TABLE urls
+ADD COLUMN active_from TIMESTAMPTZ;
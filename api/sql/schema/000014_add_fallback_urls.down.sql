@@ -0,0 +1,6 @@
+DROP INDEX idx_urls_short_code;
+
+DROP TABLE user_settings;
+
+ALTER TABLE urls
+DROP COLUMN fallback_url;
@@ -0,0 +1,17 @@
+-- Where visitors go when a link is expired, disabled, over its click cap or
+-- not live yet. NULL falls back to the owner's default.
+ALTER TABLE urls
+ADD COLUMN fallback_url TEXT;
+
+-- Per-user defaults. Users live in the auth provider, so rows are created on
+-- first write.
+CREATE TABLE user_settings (
+    user_id VARCHAR(255) PRIMARY KEY,
+    fallback_url TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The redirector also resolves inactive links, which the partial unique
+-- index on active short codes does not cover.
+CREATE INDEX idx_urls_short_code ON urls (short_code)
+WHERE deleted_at IS NULL;
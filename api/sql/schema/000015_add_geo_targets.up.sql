@@ -0,0 +1,4 @@
+-- Per-country destination overrides keyed by ISO 3166-1 alpha-2 code,
+-- e.g. {"DE": "https://example.com/de"}. NULL means no overrides.
+ALTER TABLE urls
+ADD COLUMN geo_targets JSONB;
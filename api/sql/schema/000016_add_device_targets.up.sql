@@ -0,0 +1,5 @@
+-- Per-platform destinations keyed by 'ios', 'android' or 'desktop', e.g.
+-- {"ios": {"url": "https://apps.apple.com/...", "deep_link": "myapp://home"}}.
+-- NULL means no device targeting.
+ALTER TABLE urls
+ADD COLUMN device_targets JSONB;
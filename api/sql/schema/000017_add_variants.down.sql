@@ -0,0 +1,3 @@
+ALTER TABLE urls
+DROP COLUMN sticky_variants,
+DROP COLUMN variants;
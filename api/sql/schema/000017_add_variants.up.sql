@@ -0,0 +1,7 @@
+-- Weighted destinations for A/B tests, e.g.
+-- [{"key": "a", "url": "https://example.com/a", "weight": 70}, ...].
+-- NULL means every visitor goes to original_url.
+ALTER TABLE urls
+ADD COLUMN variants JSONB,
+-- Keep returning visitors on the variant they saw first.
+ADD COLUMN sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,32 @@
+-- Asynchronous raw click exports. The worker claims pending rows, writes
+-- the file to the export store and records it here until it expires.
+CREATE TABLE exports (
+    id BIGINT PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL,
+
+    -- NULL exports every link of the user.
+    url_id BIGINT REFERENCES urls (id) ON DELETE CASCADE,
+    short_code VARCHAR(20) COLLATE "C",
+
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'parquet')),
+    range_from TIMESTAMPTZ NOT NULL,
+    range_to TIMESTAMPTZ NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    -- Name of the file in the export store once completed.
+    file_name TEXT,
+    size_bytes BIGINT,
+    error TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    -- The file and row are deleted after this time.
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_exports_user_id_created_at ON exports (user_id, created_at DESC);
+
+CREATE INDEX idx_exports_queue ON exports (created_at)
+WHERE status IN ('pending', 'running');
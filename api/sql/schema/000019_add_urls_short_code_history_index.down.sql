@@ -0,0 +1 @@
+DROP INDEX idx_urls_short_code_created_at;
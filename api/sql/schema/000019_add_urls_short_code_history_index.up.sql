@@ -0,0 +1,3 @@
+-- Click analytics look up the links that held a short code before and after
+-- a given one, deleted links included.
+CREATE INDEX idx_urls_short_code_created_at ON urls (short_code, created_at);